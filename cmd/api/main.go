@@ -1,6 +1,9 @@
 package main
 
 import (
+	// Embedded so that profile time zones validate on images without zoneinfo
+	_ "time/tzdata"
+
 	_ "github.com/YoubaImkf/go-auth-api/docs"
 	"github.com/YoubaImkf/go-auth-api/internal/app"
 )
@@ -13,6 +16,9 @@ import (
 // @securityDefinitions.apikey Bearer
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey AdminKey
+// @in header
+// @name X-Admin-Key
 func main() {
 	application := app.New()
 	application.Run()
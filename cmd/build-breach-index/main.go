@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/YoubaImkf/go-auth-api/internal/app"
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+)
+
+// Builds the breached password index read at breached_passwords.index_path from
+// the Have I Been Pwned SHA-1 file ordered by hash. Restart the API afterwards
+// so it opens the new index.
+func main() {
+	app.LoadConfig()
+
+	source := flag.String("source", "", "Have I Been Pwned SHA-1 file, ordered by hash")
+	minCount := flag.Int("min-count", 1, "leave out passwords seen fewer times than this")
+	flag.Parse()
+
+	if *source == "" {
+		log.Fatal("-source must point at the downloaded password list")
+	}
+
+	indexPath := viper.GetString("breached_passwords.index_path")
+	if indexPath == "" {
+		log.Fatal("breached_passwords.index_path must be set to build the index")
+	}
+
+	written, err := service.BuildBreachedPasswordIndex(*source, indexPath, *minCount)
+	if err != nil {
+		log.Fatalf("Failed to build the breached password index: %s", err)
+	}
+
+	log.Printf("Wrote %d breached password hashes to %s", written, indexPath)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/YoubaImkf/go-auth-api/internal/app"
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+)
+
+// Rotates the signing keys in jwt.keyring_path in two steps. Without flags it
+// generates the next key, which verifies tokens and is published in the JWKS
+// but does not sign yet. Once every instance has loaded it, -promote makes it
+// the active key; the previous key keeps verifying tokens for the lifetime of
+// a refresh token. Instances reload the key ring every
+// jwt.keyring_reload_interval.
+func main() {
+	app.LoadConfig()
+
+	algorithm := flag.String("algorithm", viper.GetString("jwt.algorithm"), "signing algorithm of the new key (HS256, RS256, ES256 or EdDSA)")
+	promote := flag.Bool("promote", false, "promote the next key to active instead of generating one")
+	flag.Parse()
+
+	keyRingPath := viper.GetString("jwt.keyring_path")
+	if keyRingPath == "" {
+		log.Fatal("jwt.keyring_path must be set to rotate signing keys")
+	}
+
+	if !*promote {
+		keyID, err := service.AddSigningKey(keyRingPath, *algorithm)
+		if err != nil {
+			log.Fatalf("Failed to add a signing key: %s", err)
+		}
+
+		log.Printf("Added signing key %s, promote it with -promote once every instance has reloaded the key ring (every %s)", keyID, viper.GetDuration("jwt.keyring_reload_interval"))
+		return
+	}
+
+	retireAfter := viper.GetDuration("jwt.refresh_token_expiry")
+	if accessTokenExpiry := viper.GetDuration("jwt.access_token_expiry"); accessTokenExpiry > retireAfter {
+		retireAfter = accessTokenExpiry
+	}
+
+	keyID, err := service.PromoteSigningKey(keyRingPath, retireAfter)
+	if err != nil {
+		log.Fatalf("Failed to promote the signing key: %s", err)
+	}
+
+	log.Printf("Promoted signing key %s, previous keys retire in %s", keyID, retireAfter)
+}
@@ -1,9 +1,16 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/YoubaImkf/go-auth-api/docs"
 	"github.com/YoubaImkf/go-auth-api/internal/controller"
@@ -21,8 +28,17 @@ import (
 )
 
 type App struct {
-	router *gin.Engine
-	db     *gorm.DB
+	router  *gin.Engine
+	db      *gorm.DB
+	janitor *service.Janitor
+}
+
+// repositories are built once since each constructor runs its migrations.
+type repositories struct {
+	blacklist    repository.BlacklistRepository
+	refreshToken repository.RefreshTokenRepository
+	session      repository.SessionRepository
+	user         repository.UserRepository
 }
 
 func New() *App {
@@ -30,15 +46,23 @@ func New() *App {
 		router: gin.Default(),
 	}
 
-	app.loadEnv()
-	app.loadConfig()
+	LoadConfig()
 	app.initDB()
 	app.InitSwaggerHost()
-	app.setupRoutes()
+	repos := app.setupRepositories()
+	app.setupRoutes(repos)
+	app.setupJanitor(repos)
 	return app
 }
 
-func (a *App) loadEnv() {
+// LoadConfig reads the .env file outside production and configs/config.yaml
+// into viper.
+func LoadConfig() {
+	loadEnv()
+	loadConfig()
+}
+
+func loadEnv() {
 	if os.Getenv("APP_ENVIRONMENT") != "production" {
 		// In development, load environment variables from .env
 		if err := godotenv.Load(); err != nil {
@@ -47,11 +71,13 @@ func (a *App) loadEnv() {
 	}
 }
 
-func (a *App) loadConfig() {
+func loadConfig() {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./configs")
 
+	// Let nested keys such as jwt.secret be set from JWT_SECRET
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -75,7 +101,7 @@ func (a *App) initDB() {
 	a.db = db
 
 	// Auto Migrate the User model an PasswordReset to create the tables
-	if err := a.db.AutoMigrate(&model.User{}, &model.PasswordReset{}).Error; err != nil {
+	if err := a.db.AutoMigrate(&model.User{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.EmailChange{}, &model.PasswordHistory{}, &model.AccountUnlock{}).Error; err != nil {
 		log.Fatalf("Failed to auto-migrate models: %s", err)
 	}
 }
@@ -86,18 +112,62 @@ func (a *App) InitSwaggerHost() {
 	}
 }
 
-func (a *App) setupRoutes() {
+func (a *App) setupRepositories() repositories {
+	var blacklistRepo repository.BlacklistRepository = repository.NewPostgresBlacklistRepository(a.db)
+	if viper.GetBool("blacklist_cache.enabled") {
+		blacklistRepo = repository.NewCachedBlacklistRepository(
+			blacklistRepo,
+			viper.GetInt("blacklist_cache.size"),
+			viper.GetDuration("blacklist_cache.ttl"),
+			viper.GetDuration("blacklist_cache.bloom_refresh_interval"),
+		)
+	}
+
+	var sessionRepo repository.SessionRepository = repository.NewPostgresSessionRepository(a.db)
+	var userRepo repository.UserRepository = repository.NewPostgresUserRepository(a.db)
+	if viper.GetBool("auth_cache.enabled") {
+		sessionRepo = repository.NewCachedSessionRepository(sessionRepo, viper.GetInt("auth_cache.size"), viper.GetDuration("auth_cache.ttl"))
+		userRepo = repository.NewCachedUserRepository(userRepo, viper.GetInt("auth_cache.size"), viper.GetDuration("auth_cache.ttl"))
+	}
+
+	return repositories{
+		blacklist:    blacklistRepo,
+		refreshToken: repository.NewPostgresRefreshTokenRepository(a.db),
+		session:      sessionRepo,
+		user:         userRepo,
+	}
+}
+
+func (a *App) setupRoutes(repos repositories) {
 	groupUUID := viper.GetString("group.uuid")
 
-	blacklistRepo := repository.NewPostgresBlacklistRepository(a.db)
-	userRepo := repository.NewPostgresUserRepository(a.db)
+	// Client IPs key the rate limits, only trusted proxies may set them
+	if err := a.router.SetTrustedProxies(viper.GetStringSlice("rate_limit.trusted_proxies")); err != nil {
+		log.Fatalf("Failed to configure trusted proxies: %s", err)
+	}
+
 	emailService := service.NewEmailService()
-	authService := service.NewAuthService(userRepo, blacklistRepo, emailService)
-	userService := service.NewUserService(userRepo)
+	tokenService, err := service.NewTokenService()
+	if err != nil {
+		log.Fatalf("Failed to load token signing key: %s", err)
+	}
+	breachedPasswords, err := service.NewBreachedPasswordChecker()
+	if err != nil {
+		log.Fatalf("Failed to load the breached password index: %s", err)
+	}
+	passwordPolicy := service.NewPasswordPolicy(breachedPasswords)
+	passwordHasher, err := service.NewPasswordHasher()
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %s", err)
+	}
+	authService := service.NewAuthService(repos.user, repos.blacklist, repos.refreshToken, repos.session, emailService, tokenService, passwordPolicy, passwordHasher)
+	userService := service.NewUserService(repos.user, repos.blacklist, repos.refreshToken, repos.session)
+	rateLimitStore := repository.NewMemoryRateLimitStore()
 
 	healthController := controller.NewHealthController()
 	authController := controller.NewAuthController(authService)
 	userController := controller.NewUserController(userService)
+	jwksController := controller.NewJWKSController(tokenService)
 
 	a.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -105,20 +175,71 @@ func (a *App) setupRoutes() {
 	apiGroup := a.router.Group(groupUUID)
 
 	apiGroup.GET("/health", healthController.Health)
+	apiGroup.GET("/.well-known/jwks.json", jwksController.GetJWKS)
 	apiGroup.GET("/users", userController.GetAllUsers)
 	apiGroup.DELETE("/remove-users", userController.RemoveAllUsers)
 
-	apiGroup.POST("/register", authController.Register)
-	apiGroup.POST("/login", authController.Login)
-	apiGroup.POST("/forgot-password", authController.ForgotPassword)
-	apiGroup.POST("/reset-password", authController.ResetPassword)
+	apiGroup.POST("/register", middleware.RateLimitMiddleware(rateLimitStore, "register"), authController.Register)
+	apiGroup.POST("/login", middleware.RateLimitMiddleware(rateLimitStore, "login"), authController.Login)
+	apiGroup.POST("/refresh", authController.RefreshToken)
+	apiGroup.POST("/forgot-password", middleware.RateLimitMiddleware(rateLimitStore, "forgot_password"), authController.ForgotPassword)
+	apiGroup.POST("/reset-password", middleware.RateLimitMiddleware(rateLimitStore, "reset_password"), authController.ResetPassword)
+	apiGroup.POST("/verify-email", authController.VerifyEmail)
+	apiGroup.POST("/resend-verification", middleware.RateLimitMiddleware(rateLimitStore, "resend_verification"), authController.ResendVerification)
+	apiGroup.POST("/undo-email-change", authController.UndoEmailChange)
+	apiGroup.POST("/unlock-account", authController.UnlockAccount)
+
+	admin := apiGroup.Group("/")
+	admin.Use(middleware.AdminMiddleware())
+	admin.POST("/users/:id/revoke-tokens", userController.RevokeTokens)
+	admin.POST("/users/:id/unlock", userController.UnlockAccount)
 
 	protected := apiGroup.Group("/")
-	protected.Use(middleware.AuthMiddleware(blacklistRepo))
+	protected.Use(middleware.AuthMiddleware(tokenService, repos.blacklist, repos.user, repos.session))
 	protected.POST("/logout", authController.Logout)
 	protected.GET("/me", authController.GetProfile)
+	protected.PATCH("/me", authController.UpdateProfile)
+	protected.DELETE("/me", authController.DeleteAccount)
+	protected.POST("/me/password", authController.ChangePassword)
+	protected.POST("/me/email", authController.RequestEmailChange)
+	protected.POST("/me/email/confirm", authController.ConfirmEmailChange)
+	protected.GET("/sessions", authController.ListSessions)
+	protected.DELETE("/sessions/:id", authController.RevokeSession)
+	protected.DELETE("/sessions", authController.RevokeAllSessions)
 }
 
+func (a *App) setupJanitor(repos repositories) {
+	a.janitor = service.NewJanitor(repos.blacklist, repos.user, repos.refreshToken, repos.session)
+}
+
+// Run serves the API until SIGINT or SIGTERM, then drains in-flight requests
+// and stops the background jobs.
 func (a *App) Run() {
-	a.router.Run(":8080")
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: a.router,
+	}
+
+	a.janitor.Start()
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %s", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down server...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shut down: %s", err)
+	}
+
+	a.janitor.Stop()
+	a.db.Close()
 }
@@ -1,14 +1,19 @@
 package controller
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 
 	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/YoubaImkf/go-auth-api/internal/model"
 	"github.com/YoubaImkf/go-auth-api/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
+const refreshTokenCookie = "refresh_token"
+
 type AuthController struct {
 	authService *service.AuthService
 }
@@ -26,7 +31,9 @@ func NewAuthController(authService *service.AuthService) *AuthController {
 // @Produce      json
 // @Param        user  body  dto.RegisterRequest  true  "User"
 // @Success      201  {object}  dto.RegisterResponse
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse  "Password does not meet the policy"
 // @Failure      409  {object}  map[string]interface{}  "User already exists"
+// @Failure      429  {object}  map[string]interface{}  "Too many requests"
 // @Router       /register [post]
 func (c *AuthController) Register(ctx *gin.Context) {
 	var registerRequest dto.RegisterRequest
@@ -36,8 +43,11 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		return
 	}
 
-	user, accessToken, refreshToken, err := c.authService.Register(registerRequest)
+	user, accessToken, refreshToken, err := c.authService.Register(registerRequest, clientInfo(ctx))
 	if err != nil {
+		if passwordPolicyError(ctx, err) {
+			return
+		}
 		if err.Error() == "user already exists" {
 			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
@@ -47,10 +57,7 @@ func (c *AuthController) Register(ctx *gin.Context) {
 	}
 
 	response := dto.RegisterResponse{
-		User: dto.UserResponse{
-			Name:  user.Name,
-			Email: user.Email,
-		},
+		User:         userResponse(user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}
@@ -59,12 +66,16 @@ func (c *AuthController) Register(ctx *gin.Context) {
 }
 
 // @Summary      Login user
-// @Description  Authenticate a user
+// @Description  Authenticate a user with their name or email address. Repeated failures slow down and then temporarily lock the account, which answers like invalid credentials meanwhile.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        user  body  dto.LoginRequest  true  "User"
 // @Success      200  {object}  dto.LoginResponse
+// @Failure      400  {object}  map[string]interface{}  "Missing identifier"
+// @Failure      401  {object}  map[string]interface{}  "Invalid credentials"
+// @Failure      403  {object}  map[string]interface{}  "Email not verified"
+// @Failure      429  {object}  map[string]interface{}  "Too many requests"
 // @Router       /login [post]
 func (c *AuthController) Login(ctx *gin.Context) {
 	var loginRequest dto.LoginRequest
@@ -74,17 +85,53 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		return
 	}
 
-	user, accessToken, refreshToken, err := c.authService.Login(loginRequest)
+	user, accessToken, refreshToken, err := c.authService.Login(loginRequest, clientInfo(ctx))
 	if err != nil {
+		if err.Error() == "identifier is required" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if err.Error() == "email not verified" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := dto.LoginResponse{
-		User: dto.UserResponse{
-			Name:  user.Name,
-			Email: user.Email,
-		},
+		User:         userResponse(user),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access and refresh token pair
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refreshTokenRequest  body  dto.RefreshTokenRequest  true  "Refresh Token"
+// @Success      200  {object}  dto.RefreshTokenResponse
+// @Failure      401  {object}  map[string]interface{}  "Invalid refresh token"
+// @Router       /refresh [post]
+func (c *AuthController) RefreshToken(ctx *gin.Context) {
+	var refreshTokenRequest dto.RefreshTokenRequest
+
+	if err := ctx.ShouldBindJSON(&refreshTokenRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, accessToken, refreshToken, err := c.authService.RefreshToken(refreshTokenRequest.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := dto.RefreshTokenResponse{
+		User:         userResponse(user),
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}
@@ -93,10 +140,13 @@ func (c *AuthController) Login(ctx *gin.Context) {
 }
 
 // @Summary      Logout user
-// @Description  Logout a user
+// @Description  Logout a user. The refresh token can be sent in the body or the refresh_token cookie to revoke it too.
 // @Tags         auth
+// @Accept       json
 // @Produce      json
+// @Param        logoutRequest  body  dto.LogoutRequest  false  "Refresh Token"
 // @Success      204  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}  "Invalid refresh token"
 // @Router       /logout [post]
 // @Security     Bearer
 func (c *AuthController) Logout(ctx *gin.Context) {
@@ -112,8 +162,24 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 		return
 	}
 
-	err := c.authService.Logout(tokenString)
+	// The body is optional, an empty one is not an error
+	var logoutRequest dto.LogoutRequest
+	if err := ctx.ShouldBindJSON(&logoutRequest); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken := logoutRequest.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = ctx.Cookie(refreshTokenCookie)
+	}
+
+	err := c.authService.Logout(tokenString, refreshToken)
 	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -129,26 +195,321 @@ func (c *AuthController) Logout(ctx *gin.Context) {
 // @Router       /me [get]
 // @Security     Bearer
 func (c *AuthController) GetProfile(ctx *gin.Context) {
-	userEmail, exists := ctx.Get("user")
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	user, err := c.authService.GetUserProfile(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary      Update user profile
+// @Description  Update the profile of the logged-in user, only the fields present are changed
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        updateProfileRequest  body  dto.UpdateProfileRequest  true  "Update Profile"
+// @Success      200  {object}  dto.UserResponse
+// @Failure      400  {object}  map[string]interface{}  "Invalid field"
+// @Failure      409  {object}  map[string]interface{}  "Name already taken"
+// @Router       /me [patch]
+// @Security     Bearer
+func (c *AuthController) UpdateProfile(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var updateProfileRequest dto.UpdateProfileRequest
+	if err := ctx.ShouldBindJSON(&updateProfileRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := c.authService.UpdateProfile(userID.(string), updateProfileRequest)
+	if err != nil {
+		if err.Error() == "name already taken" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if strings.HasPrefix(err.Error(), "invalid ") {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userResponse(user))
+}
+
+// @Summary      Delete account
+// @Description  Delete the account of the logged-in user, logging in again before the deletion date restores it
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        deleteAccountRequest  body  dto.DeleteAccountRequest  true  "Delete Account"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid current password"
+// @Router       /me [delete]
+// @Security     Bearer
+func (c *AuthController) DeleteAccount(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
 		return
 	}
 
-	user, err := c.authService.GetUserProfile(userEmail.(string))
+	var deleteAccountRequest dto.DeleteAccountRequest
+	if err := ctx.ShouldBindJSON(&deleteAccountRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deletionDate, err := c.authService.DeleteAccount(userID.(string), deleteAccountRequest)
 	if err != nil {
+		if err.Error() == "invalid current password" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	response := dto.UserResponse{
-		Name:  user.Name,
-		Email: user.Email,
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account scheduled for deletion", "deletion_date": deletionDate})
+}
+
+// @Summary      Change password
+// @Description  Change the password of the logged-in user and sign out every other session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        changePasswordRequest  body  dto.ChangePasswordRequest  true  "Change Password"
+// @Success      200  {object}  dto.ChangePasswordResponse
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse  "New password must be different or does not meet the policy"
+// @Failure      401  {object}  map[string]interface{}  "Invalid current password"
+// @Router       /me/password [post]
+// @Security     Bearer
+func (c *AuthController) ChangePassword(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	sessionID, _ := ctx.Get("session")
+
+	var changePasswordRequest dto.ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&changePasswordRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentSessionID, _ := sessionID.(string)
+	accessToken, refreshToken, err := c.authService.ChangePassword(userID.(string), currentSessionID, changePasswordRequest)
+	if err != nil {
+		if passwordPolicyError(ctx, err) {
+			return
+		}
+		if err.Error() == "invalid current password" || err.Error() == "session not found" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if err.Error() == "new password must be different from the current password" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	response := dto.ChangePasswordResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}
 
 	ctx.JSON(http.StatusOK, response)
 }
 
+// @Summary      Request email change
+// @Description  Send a confirmation link to the new email address of the logged-in user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        changeEmailRequest  body  dto.ChangeEmailRequest  true  "Change Email"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}  "New email must be different"
+// @Failure      401  {object}  map[string]interface{}  "Invalid current password"
+// @Failure      409  {object}  map[string]interface{}  "Email already in use"
+// @Router       /me/email [post]
+// @Security     Bearer
+func (c *AuthController) RequestEmailChange(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	var changeEmailRequest dto.ChangeEmailRequest
+	if err := ctx.ShouldBindJSON(&changeEmailRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := c.authService.RequestEmailChange(userID.(string), changeEmailRequest)
+	if err != nil {
+		if err.Error() == "invalid current password" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if err.Error() == "new email must be different from the current email" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err.Error() == "email already in use" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Confirmation email sent"})
+}
+
+// @Summary      Confirm email change
+// @Description  Move the logged-in user to the new email address and sign out every other session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        confirmEmailChangeRequest  body  dto.ConfirmEmailChangeRequest  true  "Confirm Email Change"
+// @Success      200  {object}  dto.ConfirmEmailChangeResponse
+// @Failure      401  {object}  map[string]interface{}  "Invalid or expired email change token"
+// @Failure      409  {object}  map[string]interface{}  "Email already in use"
+// @Router       /me/email/confirm [post]
+// @Security     Bearer
+func (c *AuthController) ConfirmEmailChange(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	sessionID, _ := ctx.Get("session")
+
+	var confirmEmailChangeRequest dto.ConfirmEmailChangeRequest
+	if err := ctx.ShouldBindJSON(&confirmEmailChangeRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentSessionID, _ := sessionID.(string)
+	user, accessToken, refreshToken, err := c.authService.ConfirmEmailChange(userID.(string), currentSessionID, confirmEmailChangeRequest.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired email change token" || err.Error() == "session not found" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if err.Error() == "email already in use" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	response := dto.ConfirmEmailChangeResponse{
+		User:         userResponse(user),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary      List sessions
+// @Description  List the active sessions of the logged-in user
+// @Tags         sessions
+// @Produce      json
+// @Success      200  {array}  dto.SessionResponse
+// @Router       /sessions [get]
+// @Security     Bearer
+func (c *AuthController) ListSessions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+	currentSessionID, _ := ctx.Get("session")
+
+	sessions, err := c.authService.ListSessions(userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary      Revoke session
+// @Description  Sign out one session of the logged-in user
+// @Tags         sessions
+// @Produce      json
+// @Param        id  path  string  true  "Session ID"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}  "Session not found"
+// @Router       /sessions/{id} [delete]
+// @Security     Bearer
+func (c *AuthController) RevokeSession(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	err := c.authService.RevokeSession(userID.(string), ctx.Param("id"))
+	if err != nil {
+		if err.Error() == "session not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Session revoked"})
+}
+
+// @Summary      Revoke all sessions
+// @Description  Sign out every session of the logged-in user, including the current one
+// @Tags         sessions
+// @Produce      json
+// @Success      204  {object}  map[string]interface{}
+// @Router       /sessions [delete]
+// @Security     Bearer
+func (c *AuthController) RevokeAllSessions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		return
+	}
+
+	if err := c.authService.RevokeAllSessions(userID.(string)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "All sessions revoked"})
+}
+
 // @Summary      Forgot password
 // @Description  Request a password reset
 // @Tags         auth
@@ -156,6 +517,7 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 // @Produce      json
 // @Param        email  body  dto.ForgotPasswordRequest  true  "Email"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      429  {object}  map[string]interface{}  "Too many requests"
 // @Router       /forgot-password [post]
 func (c *AuthController) ForgotPassword(ctx *gin.Context) {
 	var forgotPasswordRequest dto.ForgotPasswordRequest
@@ -165,13 +527,12 @@ func (c *AuthController) ForgotPassword(ctx *gin.Context) {
 		return
 	}
 
-	token, err := c.authService.ForgotPassword(forgotPasswordRequest.Email)
-	if err != nil {
+	if err := c.authService.ForgotPassword(forgotPasswordRequest.Email); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Password reset link sent", "token": token})
+	ctx.JSON(http.StatusOK, gin.H{"message": "If the address belongs to an account, a password reset link was sent"})
 }
 
 // @Summary      Reset password
@@ -181,6 +542,9 @@ func (c *AuthController) ForgotPassword(ctx *gin.Context) {
 // @Produce      json
 // @Param        resetPasswordRequest  body  dto.ResetPasswordRequest  true  "Reset Password"
 // @Success      204  {object}  map[string]interface{}
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse  "Password does not meet the policy"
+// @Failure      401  {object}  map[string]interface{}  "Invalid or expired reset token"
+// @Failure      429  {object}  map[string]interface{}  "Too many requests"
 // @Router       /reset-password [post]
 func (c *AuthController) ResetPassword(ctx *gin.Context) {
 	var resetPasswordRequest dto.ResetPasswordRequest
@@ -192,6 +556,9 @@ func (c *AuthController) ResetPassword(ctx *gin.Context) {
 
 	err := c.authService.ResetPassword(resetPasswordRequest)
 	if err != nil {
+		if passwordPolicyError(ctx, err) {
+			return
+		}
 		if err.Error() == "invalid or expired reset token" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
@@ -202,3 +569,160 @@ func (c *AuthController) ResetPassword(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, gin.H{"message": "Password has been reset"})
 }
+
+// @Summary      Undo email change
+// @Description  Restore the previous email address with the link sent to it and sign out every session
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        undoEmailChangeRequest  body  dto.UndoEmailChangeRequest  true  "Undo Email Change"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid or expired undo token"
+// @Failure      409  {object}  map[string]interface{}  "Email already in use"
+// @Router       /undo-email-change [post]
+func (c *AuthController) UndoEmailChange(ctx *gin.Context) {
+	var undoEmailChangeRequest dto.UndoEmailChangeRequest
+
+	if err := ctx.ShouldBindJSON(&undoEmailChangeRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := c.authService.UndoEmailChange(undoEmailChangeRequest.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired undo token" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else if err.Error() == "email already in use" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Email change has been undone"})
+}
+
+// @Summary      Verify email
+// @Description  Confirm the user's email address with the token sent on registration
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        verifyEmailRequest  body  dto.VerifyEmailRequest  true  "Verify Email"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid or expired verification token"
+// @Router       /verify-email [post]
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var verifyEmailRequest dto.VerifyEmailRequest
+
+	if err := ctx.ShouldBindJSON(&verifyEmailRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := c.authService.VerifyEmail(verifyEmailRequest.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired verification token" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Email has been verified"})
+}
+
+// @Summary      Unlock account
+// @Description  Lift the lockout after too many failed logins with the token sent by email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        unlockAccountRequest  body  dto.UnlockAccountRequest  true  "Unlock Account"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid or expired unlock token"
+// @Router       /unlock-account [post]
+func (c *AuthController) UnlockAccount(ctx *gin.Context) {
+	var unlockAccountRequest dto.UnlockAccountRequest
+
+	if err := ctx.ShouldBindJSON(&unlockAccountRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := c.authService.UnlockAccount(unlockAccountRequest.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired unlock token" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Account has been unlocked"})
+}
+
+// @Summary      Resend verification email
+// @Description  Send a new email verification link to an unverified user. The answer is the same whether or not the address belongs to an unverified account.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        resendVerificationRequest  body  dto.ResendVerificationRequest  true  "Resend Verification"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      429  {object}  map[string]interface{}  "Too many requests"
+// @Router       /resend-verification [post]
+func (c *AuthController) ResendVerification(ctx *gin.Context) {
+	var resendVerificationRequest dto.ResendVerificationRequest
+
+	if err := ctx.ShouldBindJSON(&resendVerificationRequest); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.authService.ResendVerification(resendVerificationRequest.Email); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "If the address belongs to an unverified account, a verification email was sent"})
+}
+
+// --- Private Methods ---
+
+func userResponse(user *model.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:          user.UUID,
+		Name:        user.Name,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		Locale:      user.Locale,
+		TimeZone:    user.TimeZone,
+		Metadata:    user.Metadata,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+}
+
+// passwordPolicyError answers with every broken rule when err comes from the
+// password policy and reports whether it did.
+func passwordPolicyError(ctx *gin.Context, err error) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	ctx.JSON(http.StatusBadRequest, dto.PasswordPolicyErrorResponse{
+		Error:      policyErr.Error(),
+		Violations: policyErr.Violations,
+	})
+	return true
+}
+
+func clientInfo(ctx *gin.Context) dto.ClientInfo {
+	return dto.ClientInfo{
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+	}
+}
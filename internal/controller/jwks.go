@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSController struct {
+	tokenService *service.TokenService
+}
+
+func NewJWKSController(tokenService *service.TokenService) *JWKSController {
+	return &JWKSController{
+		tokenService: tokenService,
+	}
+}
+
+// @Summary      JSON Web Key Set
+// @Description  Get the public keys used to verify issued tokens
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  dto.JWKSResponse
+// @Router       /.well-known/jwks.json [get]
+func (c *JWKSController) GetJWKS(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.tokenService.JWKS())
+}
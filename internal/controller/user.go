@@ -46,3 +46,47 @@ func (c *UserController) RemoveAllUsers(ctx *gin.Context) {
 	}
 	ctx.Status(http.StatusOK)
 }
+
+// @Summary      Revoke a user's tokens
+// @Description  Reject every access and refresh token issued to the user until now and end their sessions
+// @Tags         user
+// @Produce      json
+// @Security     AdminKey
+// @Param        id  path  string  true  "User ID"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid admin key"
+// @Failure      404  {object}  map[string]interface{}  "User not found"
+// @Router       /users/{id}/revoke-tokens [post]
+func (c *UserController) RevokeTokens(ctx *gin.Context) {
+	if err := c.userService.RevokeTokens(ctx.Param("id")); err != nil {
+		if err.Error() == "user not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Tokens revoked"})
+}
+
+// @Summary      Unlock a user's account
+// @Description  Lift the lockout of a user after too many failed logins
+// @Tags         user
+// @Produce      json
+// @Security     AdminKey
+// @Param        id  path  string  true  "User ID"
+// @Success      204  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}  "Invalid admin key"
+// @Failure      404  {object}  map[string]interface{}  "User not found"
+// @Router       /users/{id}/unlock [post]
+func (c *UserController) UnlockAccount(ctx *gin.Context) {
+	if err := c.userService.UnlockAccount(ctx.Param("id")); err != nil {
+		if err.Error() == "user not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusNoContent, gin.H{"message": "Account unlocked"})
+}
@@ -1,19 +1,25 @@
 package dto
 
+import "time"
+
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
 }
 
 type RegisterResponse struct {
-	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"token"`
-	RefreshToken string       `json:"refresh_token"`
+	User UserResponse `json:"user"`
+	// Tokens are omitted when login requires a verified email address
+	AccessToken  string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required"`
+	// Identifier is either the user name or the email address
+	Identifier string `json:"identifier"`
+	// Deprecated: use Identifier, kept for older clients
+	Email    string `json:"email"`
 	Password string `json:"password" binding:"required"`
 }
 
@@ -23,9 +29,42 @@ type LoginResponse struct {
 	RefreshToken string       `json:"refresh_token"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type RefreshTokenResponse struct {
+	User         UserResponse `json:"user"`
+	AccessToken  string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type UserResponse struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Email       string                 `json:"email"`
+	DisplayName string                 `json:"display_name"`
+	AvatarURL   string                 `json:"avatar_url"`
+	Locale      string                 `json:"locale"`
+	TimeZone    string                 `json:"time_zone"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// UpdateProfileRequest only changes the fields that are present. An empty
+// string clears an optional field and metadata replaces the stored map.
+type UpdateProfileRequest struct {
+	Name        *string                `json:"name" binding:"omitempty,min=1,max=100"`
+	DisplayName *string                `json:"display_name" binding:"omitempty,max=100"`
+	AvatarURL   *string                `json:"avatar_url" binding:"omitempty,max=2048"`
+	Locale      *string                `json:"locale" binding:"omitempty,max=35"`
+	TimeZone    *string                `json:"time_zone" binding:"omitempty,max=64"`
+	Metadata    map[string]interface{} `json:"metadata"`
 }
 
 type ForgotPasswordRequest struct {
@@ -34,5 +73,93 @@ type ForgotPasswordRequest struct {
 
 type ResetPasswordRequest struct {
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// PasswordRuleViolation names a password policy rule and why it failed.
+type PasswordRuleViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicyErrorResponse struct {
+	Error      string                  `json:"error"`
+	Violations []PasswordRuleViolation `json:"violations"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordResponse struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ConfirmEmailChangeResponse struct {
+	User         UserResponse `json:"user"`
+	AccessToken  string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+}
+
+type UndoEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ClientInfo describes the device a request comes from. It is filled from the
+// request headers, never from the body.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
 }
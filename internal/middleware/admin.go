@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// AdminMiddleware lets through the requests carrying the admin API key from
+// admin.api_key (ADMIN_API_KEY) in the X-Admin-Key header. Admin routes are
+// closed while no key is configured.
+func AdminMiddleware() gin.HandlerFunc {
+	apiKey := viper.GetString("admin.api_key")
+
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin API is disabled"})
+			c.Abort()
+			return
+		}
+
+		provided := c.GetHeader("X-Admin-Key")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin key"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -5,12 +5,11 @@ import (
 	"strings"
 
 	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/YoubaImkf/go-auth-api/internal/service"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
-	"github.com/spf13/viper"
 )
 
-func AuthMiddleware(blacklistRepo repository.BlacklistRepository) gin.HandlerFunc {
+func AuthMiddleware(tokenService *service.TokenService, blacklistRepo repository.BlacklistRepository, userRepo repository.UserRepository, sessionRepo repository.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -26,26 +25,49 @@ func AuthMiddleware(blacklistRepo repository.BlacklistRepository) gin.HandlerFun
 			return
 		}
 
-		if blacklistRepo.IsBlacklisted(tokenString) {
+		// Verify the signature first so forged tokens never reach storage
+		claims, err := tokenService.ParseToken(tokenString, service.AccessTokenType)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		if blacklistRepo.IsBlacklisted(claims["jti"].(string)) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is blacklisted"})
 			c.Abort()
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrInvalidKey
-			}
-			return []byte(viper.GetString("jwt.secret")), nil
-		})
+		if service.HasLegacySubject(claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token format is no longer supported, please log in again"})
+			c.Abort()
+			return
+		}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// Tokens issued before the user's last password reset or forced
+		// sign-out are rejected even though they have not expired yet
+		subject, _ := claims["sub"].(string)
+		user, err := userRepo.FindTokenCutoff(subject)
+		if err != nil || service.IssuedBeforeCutoff(user, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
 			c.Abort()
 			return
 		}
 
-		c.Set("user", token.Claims.(jwt.MapClaims)["sub"])
+		// Only the latest access token of a session is blacklisted when it is
+		// revoked, the ones issued before its last refresh are caught here
+		if sessionID, ok := claims["sid"].(string); ok && sessionID != "" {
+			revoked, err := sessionRepo.IsRevoked(sessionID, user.ID)
+			if err != nil || revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("user", claims["sub"])
+		c.Set("session", claims["sid"])
 		c.Next()
 	}
 }
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// AccountUnlock is the token mailed to a user whose account was locked after
+// too many failed logins. It lifts the lock before it expires on its own.
+type AccountUnlock struct {
+	UserID uint      `gorm:"primary_key"`
+	Token  string    `gorm:"unique"`
+	Expiry time.Time `gorm:"index"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// EmailChange is a pending or confirmed change of a user's email address. The
+// token confirms the new address, the undo token lets the old address revert
+// the change once it has been applied.
+type EmailChange struct {
+	Token       string `gorm:"primary_key"`
+	UndoToken   string `gorm:"unique"`
+	UserID      uint   `gorm:"index"`
+	OldEmail    string
+	NewEmail    string
+	Expiry      time.Time `gorm:"index"`
+	ConfirmedAt *time.Time
+	UndoExpiry  *time.Time
+	UndoneAt    *time.Time
+}
@@ -0,0 +1,9 @@
+package model
+
+import "time"
+
+type EmailVerification struct {
+	Email  string    `gorm:"primary_key"`
+	Token  string    `gorm:"unique"`
+	Expiry time.Time `gorm:"index"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// PasswordHistory is a password hash a user had before their current one,
+// kept to refuse passwords that were used recently.
+type PasswordHistory struct {
+	ID                    uint   `gorm:"primary_key"`
+	UserID                uint   `gorm:"index"`
+	Password              string `json:"-"`
+	PasswordPepperVersion int    `gorm:"not null;default:0"`
+	CreatedAt             time.Time
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RefreshToken tracks an issued refresh token by its jti. Tokens obtained by
+// rotating one another share the same FamilyID.
+type RefreshToken struct {
+	JTI       string    `gorm:"primary_key"`
+	FamilyID  string    `gorm:"index;not null"`
+	Subject   string    `gorm:"index;not null"`
+	Expiry    time.Time `gorm:"index;not null"`
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// RevokedToken blacklists a token by its jti until the token expires. The
+// token itself is never stored.
+type RevokedToken struct {
+	JTI    string    `gorm:"primary_key"`
+	Expiry time.Time `gorm:"index;not null"`
+}
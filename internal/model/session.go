@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Session is a device signed in to a user account. Its ID is shared by the
+// refresh token family and carried in the sid claim of every token it issues.
+type Session struct {
+	ID                string `gorm:"primary_key"`
+	UserID            uint   `gorm:"index;not null"`
+	UserAgent         string
+	IPAddress         string
+	AccessTokenJTI    string
+	AccessTokenExpiry time.Time
+	CreatedAt         time.Time
+	LastUsedAt        time.Time
+	RevokedAt         *time.Time
+}
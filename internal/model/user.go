@@ -1,8 +1,36 @@
 package model
 
+import "time"
+
 type User struct {
 	ID       uint   `gorm:"primary_key"`
 	Name     string `json:"name" gorm:"unique"`
 	Email    string `json:"email" gorm:"unique"`
 	Password string `json:"-"`
+	// PasswordPepperVersion is the version of the pepper mixed into Password,
+	// 0 when it was hashed without one.
+	PasswordPepperVersion int `json:"-" gorm:"not null;default:0"`
+	// UUID is the opaque identifier exposed to clients and used as the JWT
+	// subject. Unlike the email it never changes.
+	UUID string `json:"uuid" gorm:"unique_index"`
+	// EmailVerified is set once the user follows the link sent on registration.
+	EmailVerified bool `json:"email_verified" gorm:"not null;default:false"`
+	// TokensValidAfter rejects every token issued before it, whatever its expiry.
+	TokensValidAfter *time.Time `json:"-"`
+	// DeletionRequestedAt is set while a deleted account can still be restored.
+	DeletionRequestedAt *time.Time `json:"-" gorm:"index"`
+	// FailedLoginAttempts counts the failed logins since the last successful
+	// one, LastFailedLoginAt is the time of the most recent and LockedUntil
+	// refuses every login until it has passed.
+	FailedLoginAttempts int        `json:"-" gorm:"not null;default:0"`
+	LastFailedLoginAt   *time.Time `json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	Locale      string    `json:"locale"`
+	TimeZone    string    `json:"time_zone"`
+	Metadata    Metadata  `json:"metadata" gorm:"type:jsonb"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
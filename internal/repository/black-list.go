@@ -1,42 +1,142 @@
 package repository
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/YoubaImkf/go-auth-api/internal/model"
 	"github.com/jinzhu/gorm"
 )
 
+var errNotBlacklisted = errors.New("token is not blacklisted")
+
 type BlacklistRepository interface {
-	Add(token string, expiry time.Time) error
-	IsBlacklisted(token string) bool
+	Add(jti string, expiry time.Time) error
+	IsBlacklisted(jti string) bool
+	FindExpiry(jti string) (time.Time, error)
+	ActiveJTIs() ([]string, error)
+	DeleteExpired(before time.Time, limit int) (int64, error)
 }
 
 type PostgresBlacklistRepository struct {
 	db *gorm.DB
 }
 
-type BlacklistedToken struct {
-	Token  string    `gorm:"primary_key"`
-	Expiry time.Time `gorm:"index"`
+// legacyBlacklistedToken is the former blacklist table, which stored whole JWTs.
+type legacyBlacklistedToken struct {
+	Token  string
+	Expiry time.Time
+}
+
+func (legacyBlacklistedToken) TableName() string {
+	return "blacklisted_tokens"
 }
 
 func NewPostgresBlacklistRepository(db *gorm.DB) *PostgresBlacklistRepository {
-	db.AutoMigrate(&BlacklistedToken{})
+	db.AutoMigrate(&model.RevokedToken{})
+	migrateLegacyBlacklist(db)
 	return &PostgresBlacklistRepository{db: db}
 }
 
-func (r *PostgresBlacklistRepository) Add(token string, expiry time.Time) error {
-	blacklistedToken := BlacklistedToken{
-		Token:  token,
+func (r *PostgresBlacklistRepository) Add(jti string, expiry time.Time) error {
+	revokedToken := model.RevokedToken{
+		JTI:    jti,
 		Expiry: expiry,
 	}
-	return r.db.Create(&blacklistedToken).Error
+	return r.db.Save(&revokedToken).Error
 }
 
-func (r *PostgresBlacklistRepository) IsBlacklisted(token string) bool {
-	var blacklistedToken BlacklistedToken
-	if err := r.db.Where("token = ?", token).First(&blacklistedToken).Error; err != nil {
+func (r *PostgresBlacklistRepository) IsBlacklisted(jti string) bool {
+	expiry, err := r.FindExpiry(jti)
+	if err != nil {
 		return false
 	}
-	return time.Now().Before(blacklistedToken.Expiry)
+	return time.Now().Before(expiry)
+}
+
+func (r *PostgresBlacklistRepository) FindExpiry(jti string) (time.Time, error) {
+	var revokedToken model.RevokedToken
+	if err := r.db.Where("jti = ?", jti).First(&revokedToken).Error; err != nil {
+		return time.Time{}, errNotBlacklisted
+	}
+	return revokedToken.Expiry, nil
+}
+
+// ActiveJTIs returns every entry that has not expired yet.
+func (r *PostgresBlacklistRepository) ActiveJTIs() ([]string, error) {
+	var jtis []string
+	if err := r.db.Model(&model.RevokedToken{}).Where("expiry > ?", time.Now()).Pluck("jti", &jtis).Error; err != nil {
+		return nil, err
+	}
+	return jtis, nil
+}
+
+// DeleteExpired removes at most limit entries that expired before the given time.
+func (r *PostgresBlacklistRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM revoked_tokens WHERE jti IN (SELECT jti FROM revoked_tokens WHERE expiry < ? LIMIT ?)",
+		before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
+
+// migrateLegacyBlacklist moves the unexpired rows of blacklisted_tokens to
+// revoked_tokens, keeping only their jti, then drops the old table. Tokens
+// without a jti are skipped since they are rejected at parse time anyway.
+func migrateLegacyBlacklist(db *gorm.DB) {
+	if !db.HasTable(&legacyBlacklistedToken{}) {
+		return
+	}
+
+	var legacyTokens []legacyBlacklistedToken
+	if err := db.Where("expiry > ?", time.Now()).Find(&legacyTokens).Error; err != nil {
+		log.Printf("Failed to read legacy blacklist: %v", err)
+		return
+	}
+
+	migrated := 0
+	for _, legacyToken := range legacyTokens {
+		jti := jtiFromToken(legacyToken.Token)
+		if jti == "" {
+			continue
+		}
+		revokedToken := model.RevokedToken{JTI: jti, Expiry: legacyToken.Expiry}
+		if err := db.Save(&revokedToken).Error; err != nil {
+			log.Printf("Failed to migrate legacy blacklist: %v", err)
+			return
+		}
+		migrated++
+	}
+
+	if err := db.DropTable(&legacyBlacklistedToken{}).Error; err != nil {
+		log.Printf("Failed to drop legacy blacklist: %v", err)
+		return
+	}
+
+	log.Printf("Migrated %d of %d legacy blacklisted tokens to revoked_tokens", migrated, len(legacyTokens))
+}
+
+// jtiFromToken reads the jti claim of a JWT without verifying it.
+func jtiFromToken(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		JTI string `json:"jti"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.JTI
 }
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter answers "definitely not present" without false negatives.
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+func newBloomFilter(expectedItems int, falsePositiveRate float64) *bloomFilter {
+	if expectedItems < 1 {
+		expectedItems = 1
+	}
+
+	n := float64(expectedItems)
+	size := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(math.Max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &bloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+func (b *bloomFilter) add(value string) {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloomFilter) mightContain(value string) bool {
+	h1, h2 := bloomHashes(value)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives the two hashes used for double hashing from one FNV-128a sum.
+func bloomHashes(value string) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const bloomFalsePositiveRate = 0.01
+
+// CachedBlacklistRepository keeps recent positive lookups in an LRU and every
+// known jti in a bloom filter, so tokens that were never blacklisted are
+// answered without a database query. The bloom filter is rebuilt from storage
+// every bloomRefreshInterval to pick up entries added by other instances.
+type CachedBlacklistRepository struct {
+	next                 BlacklistRepository
+	ttl                  time.Duration
+	bloomRefreshInterval time.Duration
+
+	entries *lruCache[string, time.Time]
+
+	mu           sync.Mutex
+	bloom        *bloomFilter
+	bloomBuiltAt time.Time
+	rebuilding   bool
+	pending      []string
+}
+
+func NewCachedBlacklistRepository(next BlacklistRepository, size int, ttl, bloomRefreshInterval time.Duration) *CachedBlacklistRepository {
+	c := &CachedBlacklistRepository{
+		next:                 next,
+		ttl:                  ttl,
+		bloomRefreshInterval: bloomRefreshInterval,
+		entries:              newLRUCache[string, time.Time](size),
+		rebuilding:           true,
+	}
+	c.rebuildBloom()
+	return c
+}
+
+func (c *CachedBlacklistRepository) Add(jti string, expiry time.Time) error {
+	if err := c.next.Add(jti, expiry); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.bloom != nil {
+		c.bloom.add(jti)
+	}
+	if c.rebuilding {
+		c.pending = append(c.pending, jti)
+	}
+	c.mu.Unlock()
+
+	c.remember(jti, expiry)
+	return nil
+}
+
+func (c *CachedBlacklistRepository) IsBlacklisted(jti string) bool {
+	expiry, err := c.FindExpiry(jti)
+	return err == nil && time.Now().Before(expiry)
+}
+
+func (c *CachedBlacklistRepository) FindExpiry(jti string) (time.Time, error) {
+	now := time.Now()
+
+	if expiresAt, ok := c.entries.get(jti, now); ok {
+		return expiresAt, nil
+	}
+
+	c.mu.Lock()
+	if !c.rebuilding && now.Sub(c.bloomBuiltAt) > c.bloomRefreshInterval {
+		c.rebuilding = true
+		go c.rebuildBloom()
+	}
+
+	mightContain := c.bloom == nil || c.bloom.mightContain(jti)
+	c.mu.Unlock()
+
+	if !mightContain {
+		return time.Time{}, errNotBlacklisted
+	}
+
+	expiry, err := c.next.FindExpiry(jti)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c.remember(jti, expiry)
+	return expiry, nil
+}
+
+func (c *CachedBlacklistRepository) ActiveJTIs() ([]string, error) {
+	return c.next.ActiveJTIs()
+}
+
+func (c *CachedBlacklistRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	return c.next.DeleteExpired(before, limit)
+}
+
+// --- Private Methods ---
+
+// remember caches a positive lookup until the entry expires or the TTL
+// elapses, whichever comes first.
+func (c *CachedBlacklistRepository) remember(jti string, expiry time.Time) {
+	expiresAt := time.Now().Add(c.ttl)
+	if expiry.Before(expiresAt) {
+		expiresAt = expiry
+	}
+	c.entries.put(jti, expiresAt, expiresAt)
+}
+
+func (c *CachedBlacklistRepository) rebuildBloom() {
+	jtis, err := c.next.ActiveJTIs()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rebuilding = false
+	c.bloomBuiltAt = time.Now()
+	pending := c.pending
+	c.pending = nil
+
+	if err != nil {
+		log.Printf("Failed to rebuild blacklist bloom filter: %v", err)
+		return
+	}
+
+	bloom := newBloomFilter(max(2*len(jtis), 1024), bloomFalsePositiveRate)
+	for _, jti := range jtis {
+		bloom.add(jti)
+	}
+	// Entries added while the jtis were being loaded may be missing from the query
+	for _, jti := range pending {
+		bloom.add(jti)
+	}
+	c.bloom = bloom
+}
@@ -0,0 +1,48 @@
+package repository
+
+import "time"
+
+// CachedSessionRepository remembers whether recently seen sessions are
+// revoked so that authenticated requests do not query the database every
+// time. Sessions revoked through it are seen at once, the ones revoked by
+// other instances after at most ttl. Every other method goes straight to
+// storage.
+type CachedSessionRepository struct {
+	SessionRepository
+	ttl    time.Duration
+	states *lruCache[string, sessionState]
+}
+
+type sessionState struct {
+	userID  uint
+	revoked bool
+}
+
+func NewCachedSessionRepository(next SessionRepository, size int, ttl time.Duration) *CachedSessionRepository {
+	return &CachedSessionRepository{
+		SessionRepository: next,
+		ttl:               ttl,
+		states:            newLRUCache[string, sessionState](size),
+	}
+}
+
+func (c *CachedSessionRepository) IsRevoked(id string, userID uint) (bool, error) {
+	now := time.Now()
+	if state, ok := c.states.get(id, now); ok && state.userID == userID {
+		return state.revoked, nil
+	}
+
+	revoked, err := c.SessionRepository.IsRevoked(id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	c.states.put(id, sessionState{userID: userID, revoked: revoked}, now.Add(c.ttl))
+	return revoked, nil
+}
+
+func (c *CachedSessionRepository) Revoke(id string) error {
+	err := c.SessionRepository.Revoke(id)
+	c.states.remove(id)
+	return err
+}
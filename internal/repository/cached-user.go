@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+)
+
+// CachedUserRepository remembers the token cut-off of recently seen users so
+// that authenticated requests do not query the database every time. Cut-offs
+// set through it apply at once, the ones set by other instances after at most
+// ttl. Every other method goes straight to storage.
+type CachedUserRepository struct {
+	UserRepository
+	ttl     time.Duration
+	cutoffs *lruCache[string, model.User]
+}
+
+func NewCachedUserRepository(next UserRepository, size int, ttl time.Duration) *CachedUserRepository {
+	return &CachedUserRepository{
+		UserRepository: next,
+		ttl:            ttl,
+		cutoffs:        newLRUCache[string, model.User](size),
+	}
+}
+
+func (c *CachedUserRepository) FindTokenCutoff(uuid string) (*model.User, error) {
+	now := time.Now()
+	if user, ok := c.cutoffs.get(uuid, now); ok {
+		return &user, nil
+	}
+
+	user, err := c.UserRepository.FindTokenCutoff(uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cutoffs.put(uuid, *user, now.Add(c.ttl))
+	return user, nil
+}
+
+func (c *CachedUserRepository) SetTokensValidAfter(userID uint, validAfter time.Time) error {
+	err := c.UserRepository.SetTokensValidAfter(userID, validAfter)
+	c.cutoffs.removeIf(func(_ string, user model.User) bool { return user.ID == userID })
+	return err
+}
+
+func (c *CachedUserRepository) RemoveAll() error {
+	err := c.UserRepository.RemoveAll()
+	c.cutoffs.removeIf(func(string, model.User) bool { return true })
+	return err
+}
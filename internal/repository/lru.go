@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache keeps at most size entries, evicting the least recently used, and
+// drops each entry once its own expiry has passed. It is safe for concurrent
+// use. A size of 0 or less caches nothing.
+type lruCache[K comparable, V any] struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[K]*list.Element
+	lru     *list.List
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:    size,
+		entries: map[K]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// get returns the value cached for key if it has not expired at now.
+func (c *lruCache[K, V]) get(key K, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry := element.Value.(*lruEntry[K, V])
+	if !now.Before(entry.expiresAt) {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+
+	c.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+// put caches value for key until expiresAt.
+func (c *lruCache[K, V]) put(key K, value V, expiresAt time.Time) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*lruEntry[K, V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&lruEntry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// remove forgets the entry of key, if any.
+func (c *lruCache[K, V]) remove(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.lru.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// removeIf forgets every entry matching the predicate.
+func (c *lruCache[K, V]) removeIf(match func(key K, value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if match(key, element.Value.(*lruEntry[K, V]).value) {
+			c.lru.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
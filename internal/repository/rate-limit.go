@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often the in-memory store drops the buckets
+// that have refilled completely, which are no different from new ones.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitStore keeps the token buckets of the rate limiter. The in-memory
+// store only counts the requests its own instance serves, deployments running
+// several instances need a store they all share.
+type RateLimitStore interface {
+	// Take removes a token from the bucket under key when one is left. The
+	// bucket holds up to burst tokens and refills completely over period.
+	Take(key string, burst int, period time.Duration, now time.Time) (RateLimitResult, error)
+	// Refund puts back a token taken from the bucket under key, for requests
+	// refused by another bucket after this one allowed them.
+	Refund(key string, burst int, period time.Duration, now time.Time) error
+}
+
+// RateLimitResult describes a bucket after a call to Take.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, 0 when one is left.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, burst int, period time.Duration, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	rate := float64(burst) / period.Seconds()
+	bucket := s.refill(key, burst, rate, now)
+
+	var result RateLimitResult
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsToDuration((float64(burst) - bucket.tokens) / rate)
+	bucket.fullAt = now.Add(result.Reset)
+
+	return result, nil
+}
+
+func (s *MemoryRateLimitStore) Refund(key string, burst int, period time.Duration, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate := float64(burst) / period.Seconds()
+	bucket := s.refill(key, burst, rate, now)
+	bucket.tokens = math.Min(float64(burst), bucket.tokens+1)
+	bucket.fullAt = now.Add(secondsToDuration((float64(burst) - bucket.tokens) / rate))
+
+	return nil
+}
+
+// --- Private Methods ---
+
+// refill returns the bucket under key with the tokens earned since its last
+// use added. Callers must hold s.mu.
+func (s *MemoryRateLimitStore) refill(key string, burst int, rate float64, now time.Time) *tokenBucket {
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	if elapsed := now.Sub(bucket.updatedAt).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(float64(burst), bucket.tokens+elapsed*rate)
+		bucket.updatedAt = now
+	}
+	return bucket
+}
+
+// sweep drops the full buckets at most once every rateLimitSweepInterval.
+// Callers must hold s.mu.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
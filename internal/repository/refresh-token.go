@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/jinzhu/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *model.RefreshToken) error
+	FindByJTI(jti string) (*model.RefreshToken, error)
+	MarkRotated(jti string) (bool, error)
+	RevokeFamily(familyID string) error
+	DeleteExpired(before time.Time, limit int) (int64, error)
+}
+
+type PostgresRefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRefreshTokenRepository(db *gorm.DB) *PostgresRefreshTokenRepository {
+	db.AutoMigrate(&model.RefreshToken{})
+	return &PostgresRefreshTokenRepository{db: db}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(token *model.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *PostgresRefreshTokenRepository) FindByJTI(jti string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.Where("jti = ?", jti).First(&token).Error; err != nil {
+		return nil, errors.New("refresh token not found")
+	}
+	return &token, nil
+}
+
+// MarkRotated flags the token as used. It reports false when the token had
+// already been rotated or revoked, which means it is being replayed.
+func (r *PostgresRefreshTokenRepository) MarkRotated(jti string) (bool, error) {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("jti = ? AND rotated_at IS NULL AND revoked_at IS NULL", jti).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpired removes at most limit refresh tokens that expired before the given time.
+func (r *PostgresRefreshTokenRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM refresh_tokens WHERE jti IN (SELECT jti FROM refresh_tokens WHERE expiry < ? LIMIT ?)",
+		before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/jinzhu/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *model.Session) error
+	FindByID(id string) (*model.Session, error)
+	IsRevoked(id string, userID uint) (bool, error)
+	FindActiveByUserID(userID uint) ([]model.Session, error)
+	UpdateAccessToken(id, jti string, expiry time.Time) error
+	Touch(id, userAgent, ipAddress string) error
+	Revoke(id string) error
+	DeleteInactive(before time.Time, limit int) (int64, error)
+}
+
+type PostgresSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresSessionRepository(db *gorm.DB) *PostgresSessionRepository {
+	db.AutoMigrate(&model.Session{})
+	return &PostgresSessionRepository{db: db}
+}
+
+func (r *PostgresSessionRepository) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *PostgresSessionRepository) FindByID(id string) (*model.Session, error) {
+	var session model.Session
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, errors.New("session not found")
+	}
+	return &session, nil
+}
+
+// IsRevoked reports whether the session was revoked, or does not exist or
+// belong to the user.
+func (r *PostgresSessionRepository) IsRevoked(id string, userID uint) (bool, error) {
+	var session model.Session
+	err := r.db.Select("user_id, revoked_at").Where("id = ?", id).First(&session).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return session.UserID != userID || session.RevokedAt != nil, nil
+}
+
+func (r *PostgresSessionRepository) FindActiveByUserID(userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_used_at desc").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *PostgresSessionRepository) UpdateAccessToken(id, jti string, expiry time.Time) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"access_token_jti":    jti,
+		"access_token_expiry": expiry,
+	}).Error
+}
+
+func (r *PostgresSessionRepository) Touch(id, userAgent, ipAddress string) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"user_agent":   userAgent,
+		"ip_address":   ipAddress,
+		"last_used_at": time.Now(),
+	}).Error
+}
+
+func (r *PostgresSessionRepository) Revoke(id string) error {
+	return r.db.Model(&model.Session{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error
+}
+
+// DeleteInactive removes at most limit sessions that were revoked before the
+// given time, or were never revoked but last used before it.
+func (r *PostgresSessionRepository) DeleteInactive(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM sessions WHERE id IN (SELECT id FROM sessions WHERE revoked_at < ? OR (revoked_at IS NULL AND last_used_at < ?) LIMIT ?)",
+		before, before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
@@ -1,7 +1,11 @@
 package repository
 
 import (
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/YoubaImkf/go-auth-api/internal/model"
@@ -11,13 +15,41 @@ import (
 type UserRepository interface {
 	Create(user *model.User) error
 	FindByEmail(email string) (*model.User, error)
-	FindByUserNameOrEmail(identifier string) (*model.User, error)
+	FindByID(id uint) (*model.User, error)
+	FindByUUID(uuid string) (*model.User, error)
+	FindTokenCutoff(uuid string) (*model.User, error)
+	FindByName(name string) (*model.User, error)
+	UpdateProfile(user *model.User) error
+	FindByUserNameOrEmail(name, email string) (*model.User, error)
 	StorePasswordResetToken(email, token string, expiry time.Time) error
 	FindEmailByResetToken(token string) (string, error)
-	UpdatePassword(email, newPassword string) error
+	UpdatePassword(email, newPassword string, pepperVersion int) error
+	AddPasswordHistory(entry *model.PasswordHistory, keep int) error
+	FindPasswordHistory(userID uint, limit int) ([]model.PasswordHistory, error)
 	GetAll() ([]model.User, error)
 	RemoveAll() error
 	InvalidateResetToken(token string) error
+	DeleteExpiredResetTokens(before time.Time, limit int) (int64, error)
+	SetTokensValidAfter(userID uint, validAfter time.Time) error
+	StoreEmailVerificationToken(email, token string, expiry time.Time) error
+	FindEmailByVerificationToken(token string) (string, error)
+	InvalidateVerificationToken(token string) error
+	DeleteExpiredVerificationTokens(before time.Time, limit int) (int64, error)
+	MarkEmailVerified(email string) error
+	StoreEmailChange(change *model.EmailChange) error
+	FindEmailChangeByToken(token string) (*model.EmailChange, error)
+	FindEmailChangeByUndoToken(undoToken string) (*model.EmailChange, error)
+	SaveEmailChange(change *model.EmailChange) error
+	DeleteExpiredEmailChanges(before time.Time, limit int) (int64, error)
+	ChangeEmail(userID uint, oldEmail, newEmail string) error
+	ScheduleDeletion(userID uint, requestedAt time.Time) error
+	CancelDeletion(userID uint) error
+	PurgeDeletedUsers(before time.Time, limit int) (int64, error)
+	ReserveLoginAttempt(user *model.User, attempts int, at time.Time, lockedUntil *time.Time) (bool, error)
+	ClearFailedLogins(userID uint) error
+	StoreUnlockToken(userID uint, token string, expiry time.Time) error
+	FindUserIDByUnlockToken(token string) (uint, error)
+	DeleteExpiredUnlockTokens(before time.Time, limit int) (int64, error)
 }
 
 type PostgresUserRepository struct {
@@ -25,12 +57,22 @@ type PostgresUserRepository struct {
 }
 
 func NewPostgresUserRepository(db *gorm.DB) *PostgresUserRepository {
+	backfillUserUUIDs(db)
+	normalizeStoredEmails(db)
 	return &PostgresUserRepository{
 		db: db,
 	}
 }
 
 func (r *PostgresUserRepository) Create(user *model.User) error {
+	if user.UUID == "" {
+		uuid, err := newUUID()
+		if err != nil {
+			return err
+		}
+		user.UUID = uuid
+	}
+
 	if err := r.db.Create(user).Error; err != nil {
 		return err
 	}
@@ -45,14 +87,71 @@ func (r *PostgresUserRepository) FindByEmail(email string) (*model.User, error)
 	return &user, nil
 }
 
-func (r *PostgresUserRepository) FindByUserNameOrEmail(identifier string) (*model.User, error) {
+func (r *PostgresUserRepository) FindByID(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) FindByUUID(uuid string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Where("uuid = ?", uuid).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+// FindTokenCutoff loads only what the per-request token checks need: the id
+// and the tokens_valid_after cut-off of the user.
+func (r *PostgresUserRepository) FindTokenCutoff(uuid string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Select("id, uuid, tokens_valid_after").Where("uuid = ?", uuid).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) FindByName(name string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("user_name = ? OR email = ?", identifier, identifier).First(&user).Error; err != nil {
+	if err := r.db.Where("name = ?", name).First(&user).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 	return &user, nil
 }
 
+// UpdateProfile saves the fields a user can edit on their own profile.
+func (r *PostgresUserRepository) UpdateProfile(user *model.User) error {
+	return r.db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"name":         user.Name,
+		"display_name": user.DisplayName,
+		"avatar_url":   user.AvatarURL,
+		"locale":       user.Locale,
+		"time_zone":    user.TimeZone,
+		"metadata":     user.Metadata,
+	}).Error
+}
+
+// FindByUserNameOrEmail returns the user whose email or name matches. The
+// email match wins when the two belong to different users.
+func (r *PostgresUserRepository) FindByUserNameOrEmail(name, email string) (*model.User, error) {
+	var users []model.User
+	if err := r.db.Where("email = ? OR name = ?", email, name).Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New("user not found")
+	}
+
+	for _, user := range users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return &users[0], nil
+}
+
 func (r *PostgresUserRepository) StorePasswordResetToken(email, token string, expiry time.Time) error {
 	passwordReset := model.PasswordReset{
 		Email:  email,
@@ -70,8 +169,45 @@ func (r *PostgresUserRepository) FindEmailByResetToken(token string) (string, er
 	return passwordReset.Email, nil
 }
 
-func (r *PostgresUserRepository) UpdatePassword(email, newPassword string) error {
-	return r.db.Model(&model.User{}).Where("email = ?", email).Update("password", newPassword).Error
+// UpdatePassword replaces the password hash of a user along with the version
+// of the pepper it was made with.
+func (r *PostgresUserRepository) UpdatePassword(email, newPassword string, pepperVersion int) error {
+	return r.db.Model(&model.User{}).Where("email = ?", email).Updates(map[string]interface{}{
+		"password":                newPassword,
+		"password_pepper_version": pepperVersion,
+	}).Error
+}
+
+// AddPasswordHistory records a previous password hash of a user and deletes
+// the oldest entries beyond keep.
+func (r *PostgresUserRepository) AddPasswordHistory(entry *model.PasswordHistory, keep int) error {
+	tx := r.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Create(entry).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	kept := tx.Model(&model.PasswordHistory{}).Select("id").Where("user_id = ?", entry.UserID).Order("created_at DESC, id DESC").Limit(keep).SubQuery()
+	if err := tx.Where("user_id = ? AND id NOT IN ?", entry.UserID, kept).Delete(&model.PasswordHistory{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// FindPasswordHistory returns the most recent previous password hashes of a
+// user, newest first.
+func (r *PostgresUserRepository) FindPasswordHistory(userID uint, limit int) ([]model.PasswordHistory, error) {
+	var history []model.PasswordHistory
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
 func (r *PostgresUserRepository) GetAll() ([]model.User, error) {
@@ -92,3 +228,374 @@ func (r *PostgresUserRepository) RemoveAll() error {
 func (r *PostgresUserRepository) InvalidateResetToken(token string) error {
 	return r.db.Delete(&model.PasswordReset{}, "token = ?", token).Error
 }
+
+// DeleteExpiredResetTokens removes at most limit reset tokens that expired before the given time.
+func (r *PostgresUserRepository) DeleteExpiredResetTokens(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM password_resets WHERE email IN (SELECT email FROM password_resets WHERE expiry < ? LIMIT ?)",
+		before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
+
+// SetTokensValidAfter invalidates every token issued to the user before validAfter.
+func (r *PostgresUserRepository) SetTokensValidAfter(userID uint, validAfter time.Time) error {
+	result := r.db.Model(&model.User{}).Where("id = ?", userID).Update("tokens_valid_after", validAfter)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) StoreEmailVerificationToken(email, token string, expiry time.Time) error {
+	emailVerification := model.EmailVerification{
+		Email:  email,
+		Token:  token,
+		Expiry: expiry,
+	}
+	return r.db.Save(&emailVerification).Error
+}
+
+func (r *PostgresUserRepository) FindEmailByVerificationToken(token string) (string, error) {
+	var emailVerification model.EmailVerification
+	if err := r.db.Where("token = ? AND expiry > ?", token, time.Now()).First(&emailVerification).Error; err != nil {
+		return "", errors.New("invalid or expired verification token")
+	}
+	return emailVerification.Email, nil
+}
+
+func (r *PostgresUserRepository) InvalidateVerificationToken(token string) error {
+	return r.db.Delete(&model.EmailVerification{}, "token = ?", token).Error
+}
+
+// DeleteExpiredVerificationTokens removes at most limit verification tokens that expired before the given time.
+func (r *PostgresUserRepository) DeleteExpiredVerificationTokens(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM email_verifications WHERE email IN (SELECT email FROM email_verifications WHERE expiry < ? LIMIT ?)",
+		before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
+
+func (r *PostgresUserRepository) MarkEmailVerified(email string) error {
+	return r.db.Model(&model.User{}).Where("email = ?", email).Update("email_verified", true).Error
+}
+
+// StoreEmailChange records a new email change request and drops any request of
+// the same user that has not been confirmed yet.
+func (r *PostgresUserRepository) StoreEmailChange(change *model.EmailChange) error {
+	if err := r.db.Delete(&model.EmailChange{}, "user_id = ? AND confirmed_at IS NULL", change.UserID).Error; err != nil {
+		return err
+	}
+	return r.db.Create(change).Error
+}
+
+func (r *PostgresUserRepository) FindEmailChangeByToken(token string) (*model.EmailChange, error) {
+	var change model.EmailChange
+	if err := r.db.Where("token = ?", token).First(&change).Error; err != nil {
+		return nil, errors.New("invalid or expired email change token")
+	}
+	return &change, nil
+}
+
+func (r *PostgresUserRepository) FindEmailChangeByUndoToken(undoToken string) (*model.EmailChange, error) {
+	var change model.EmailChange
+	if err := r.db.Where("undo_token = ?", undoToken).First(&change).Error; err != nil {
+		return nil, errors.New("invalid or expired undo token")
+	}
+	return &change, nil
+}
+
+func (r *PostgresUserRepository) SaveEmailChange(change *model.EmailChange) error {
+	return r.db.Save(change).Error
+}
+
+// DeleteExpiredEmailChanges removes at most limit email changes that can no
+// longer be used: pending ones whose link expired and confirmed ones whose
+// undo window is over.
+func (r *PostgresUserRepository) DeleteExpiredEmailChanges(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM email_changes WHERE token IN (SELECT token FROM email_changes WHERE (confirmed_at IS NULL AND expiry < ?) OR undo_expiry < ? LIMIT ?)",
+		before, before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
+
+// ChangeEmail moves the user and their pending password reset to the new
+// address in a single transaction. Pending verification links of the old
+// address are dropped: receiving the change links proves the new one.
+func (r *PostgresUserRepository) ChangeEmail(userID uint, oldEmail, newEmail string) error {
+	tx := r.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Model(&model.User{}).Where("id = ? AND email = ?", userID, oldEmail).Updates(map[string]interface{}{
+		"email":          newEmail,
+		"email_verified": true,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&model.PasswordReset{}).Where("email = ?", oldEmail).Update("email", newEmail).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.EmailVerification{}, "email = ?", oldEmail).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// backfillUserUUIDs gives a UUID to the users created before the column existed.
+func backfillUserUUIDs(db *gorm.DB) {
+	var users []model.User
+	if err := db.Where("uuid IS NULL OR uuid = ''").Find(&users).Error; err != nil {
+		log.Printf("Failed to read users without a UUID: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		uuid, err := newUUID()
+		if err != nil {
+			log.Printf("Failed to generate a user UUID: %v", err)
+			return
+		}
+		if err := db.Model(&model.User{}).Where("id = ?", user.ID).Update("uuid", uuid).Error; err != nil {
+			log.Printf("Failed to backfill user UUIDs: %v", err)
+			return
+		}
+	}
+
+	if len(users) > 0 {
+		log.Printf("Assigned a UUID to %d existing users", len(users))
+	}
+}
+
+// normalizeStoredEmails lowercases the emails saved before they were
+// normalised. Addresses that would collide with another account once lowercased
+// are left as they are and logged so they can be merged by hand.
+func normalizeStoredEmails(db *gorm.DB) {
+	var users []model.User
+	if err := db.Where("email <> LOWER(email)").Find(&users).Error; err != nil {
+		log.Printf("Failed to read users with a mixed-case email: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		email := strings.ToLower(user.Email)
+		var count int
+		if err := db.Model(&model.User{}).Where("LOWER(email) = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+			log.Printf("Failed to normalise the email of user %s: %v", user.UUID, err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("Email of user %s collides with another account once lowercased, left unchanged", user.UUID)
+			continue
+		}
+		if err := renameStoredEmail(db, user.Email, email); err != nil {
+			log.Printf("Failed to normalise the email of user %s: %v", user.UUID, err)
+		}
+	}
+}
+
+// renameStoredEmail moves a user and the tokens keyed by their email to a new
+// address without touching anything else.
+func renameStoredEmail(db *gorm.DB, oldEmail, newEmail string) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Model(&model.User{}).Where("email = ?", oldEmail).Update("email", newEmail).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&model.PasswordReset{}).Where("email = ?", oldEmail).Update("email", newEmail).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&model.EmailVerification{}).Where("email = ?", oldEmail).Update("email", newEmail).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func (r *PostgresUserRepository) ScheduleDeletion(userID uint, requestedAt time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("deletion_requested_at", requestedAt).Error
+}
+
+func (r *PostgresUserRepository) CancelDeletion(userID uint) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Update("deletion_requested_at", gorm.Expr("NULL")).Error
+}
+
+// ReserveLoginAttempt counts a login attempt of the user as failed before its
+// password is checked, and locks the account until lockedUntil when it is set.
+// The update only applies while the account is not locked at and its failures
+// are still the ones read into user, so concurrent attempts cannot all pass
+// the lockout: it reports false when another attempt got there first.
+func (r *PostgresUserRepository) ReserveLoginAttempt(user *model.User, attempts int, at time.Time, lockedUntil *time.Time) (bool, error) {
+	var locked interface{} = gorm.Expr("NULL")
+	if lockedUntil != nil {
+		locked = *lockedUntil
+	}
+
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND failed_login_attempts = ? AND last_failed_login_at IS NOT DISTINCT FROM ?", user.ID, user.FailedLoginAttempts, user.LastFailedLoginAt).
+		Where("locked_until IS NULL OR locked_until <= ?", at).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": attempts,
+			"last_failed_login_at":  at,
+			"locked_until":          locked,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ClearFailedLogins forgets the failed logins of the user, which lifts any
+// lockout, and drops their unlock token.
+func (r *PostgresUserRepository) ClearFailedLogins(userID uint) error {
+	tx := r.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  gorm.Expr("NULL"),
+		"locked_until":          gorm.Expr("NULL"),
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.AccountUnlock{}, "user_id = ?", userID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *PostgresUserRepository) StoreUnlockToken(userID uint, token string, expiry time.Time) error {
+	accountUnlock := model.AccountUnlock{
+		UserID: userID,
+		Token:  token,
+		Expiry: expiry,
+	}
+	return r.db.Save(&accountUnlock).Error
+}
+
+func (r *PostgresUserRepository) FindUserIDByUnlockToken(token string) (uint, error) {
+	var accountUnlock model.AccountUnlock
+	if err := r.db.Where("token = ? AND expiry > ?", token, time.Now()).First(&accountUnlock).Error; err != nil {
+		return 0, errors.New("invalid or expired unlock token")
+	}
+	return accountUnlock.UserID, nil
+}
+
+// DeleteExpiredUnlockTokens removes at most limit unlock tokens that expired before the given time.
+func (r *PostgresUserRepository) DeleteExpiredUnlockTokens(before time.Time, limit int) (int64, error) {
+	result := r.db.Exec(
+		"DELETE FROM account_unlocks WHERE user_id IN (SELECT user_id FROM account_unlocks WHERE expiry < ? LIMIT ?)",
+		before, limit,
+	)
+	return result.RowsAffected, result.Error
+}
+
+// PurgeDeletedUsers erases at most limit users whose deletion was requested
+// before the given time and returns how many were removed.
+func (r *PostgresUserRepository) PurgeDeletedUsers(before time.Time, limit int) (int64, error) {
+	var users []model.User
+	if err := r.db.Where("deletion_requested_at < ?", before).Limit(limit).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for i := range users {
+		if err := r.purgeUser(&users[i]); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeUser deletes the user and every row holding their personal data. The
+// session history is kept for auditing with the IP address and user agent
+// blanked out.
+func (r *PostgresUserRepository) purgeUser(user *model.User) error {
+	tx := r.db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := tx.Delete(&model.PasswordReset{}, "email = ?", user.Email).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.EmailVerification{}, "email = ?", user.Email).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.EmailChange{}, "user_id = ?", user.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.PasswordHistory{}, "user_id = ?", user.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.AccountUnlock{}, "user_id = ?", user.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.RefreshToken{}, "subject = ?", user.UUID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&model.Session{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+		"ip_address": "",
+		"user_agent": "",
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Delete(&model.User{}, "id = ?", user.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
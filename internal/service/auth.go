@@ -4,63 +4,118 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/YoubaImkf/go-auth-api/internal/dto"
 	"github.com/YoubaImkf/go-auth-api/internal/model"
 	"github.com/YoubaImkf/go-auth-api/internal/repository"
-	"github.com/golang-jwt/jwt"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// const (
-// 	minPasswordLength = 8
-// )
-
 type AuthService struct {
-	userRepository repository.UserRepository
-	blacklistRepo  repository.BlacklistRepository
-	emailService   EmailService
-	jwtSecret      string
+	userRepository   repository.UserRepository
+	blacklistRepo    repository.BlacklistRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	sessionRepo      repository.SessionRepository
+	emailService     EmailService
+	tokenService     *TokenService
+	passwordPolicy   *PasswordPolicy
+	passwordHasher   PasswordHasher
+	revoker          *tokenRevoker
+
+	requireEmailVerification bool
+	verificationTokenExpiry  time.Duration
+	emailChangeTokenExpiry   time.Duration
+	emailChangeUndoExpiry    time.Duration
+	deletionGracePeriod      time.Duration
+	passwordHistorySize      int
+
+	lockoutMaxAttempts  int
+	lockoutFreeAttempts int
+	lockoutBaseDelay    time.Duration
+	lockoutMaxDelay     time.Duration
+	lockoutDuration     time.Duration
+
+	dummyHashOnce      sync.Once
+	dummyHash          string
+	dummyPepperVersion int
 }
 
-func NewAuthService(userRepo repository.UserRepository, blacklistRepo repository.BlacklistRepository, emailService EmailService) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, blacklistRepo repository.BlacklistRepository, refreshTokenRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, emailService EmailService, tokenService *TokenService, passwordPolicy *PasswordPolicy, passwordHasher PasswordHasher) *AuthService {
 	return &AuthService{
-		userRepository: userRepo,
-		blacklistRepo:  blacklistRepo,
-		emailService:   emailService,
-		jwtSecret:      viper.GetString("jwt.secret"),
+		userRepository:   userRepo,
+		blacklistRepo:    blacklistRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		emailService:     emailService,
+		tokenService:     tokenService,
+		passwordPolicy:   passwordPolicy,
+		passwordHasher:   passwordHasher,
+		revoker:          newTokenRevoker(userRepo, blacklistRepo, refreshTokenRepo, sessionRepo),
+
+		requireEmailVerification: viper.GetBool("email_verification.required"),
+		verificationTokenExpiry:  viper.GetDuration("email_verification.token_expiry"),
+		emailChangeTokenExpiry:   viper.GetDuration("email_change.token_expiry"),
+		emailChangeUndoExpiry:    viper.GetDuration("email_change.undo_expiry"),
+		deletionGracePeriod:      viper.GetDuration("account_deletion.grace_period"),
+		passwordHistorySize:      viper.GetInt("password_policy.history_size"),
+
+		lockoutMaxAttempts:  viper.GetInt("account_lockout.max_attempts"),
+		lockoutFreeAttempts: viper.GetInt("account_lockout.free_attempts"),
+		lockoutBaseDelay:    viper.GetDuration("account_lockout.base_delay"),
+		lockoutMaxDelay:     viper.GetDuration("account_lockout.max_delay"),
+		lockoutDuration:     viper.GetDuration("account_lockout.duration"),
 	}
 }
 
-func (s *AuthService) Register(registerRequest dto.RegisterRequest) (*model.User, string, string, error) {
-	// if err := isPasswordValid(registerRequest.Password); err != nil {
-	// 	return nil, "", "", err
-	// }
+func (s *AuthService) Register(registerRequest dto.RegisterRequest, client dto.ClientInfo) (*model.User, string, string, error) {
+	email := normalizeEmail(registerRequest.Email)
+
+	if err := s.passwordPolicy.Validate(registerRequest.Password, registerRequest.Name, email); err != nil {
+		return nil, "", "", err
+	}
 
-	existingUser, err := s.userRepository.FindByEmail(registerRequest.Email)
+	existingUser, err := s.userRepository.FindByEmail(email)
 	if err == nil && existingUser != nil {
 		return nil, "", "", errors.New("user already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(registerRequest.Password), bcrypt.DefaultCost)
+	// Names are login identifiers too, so they must be unique as well
+	if existingUser, err := s.userRepository.FindByName(registerRequest.Name); err == nil && existingUser != nil {
+		return nil, "", "", errors.New("user already exists")
+	}
+
+	hashedPassword, pepperVersion, err := s.passwordHasher.Hash(registerRequest.Password)
 	if err != nil {
 		return nil, "", "", err
 	}
 
 	user := &model.User{
 		Name:     registerRequest.Name,
-		Email:    registerRequest.Email,
-		Password: string(hashedPassword),
+		Email:    email,
+		Password: hashedPassword,
+
+		PasswordPepperVersion: pepperVersion,
 	}
 
 	if err := s.userRepository.Create(user); err != nil {
 		return nil, "", "", err
 	}
 
-	accessToken, refreshToken, err := s.generateTokens(user)
+	// The account exists at this point, a lost email can be sent again
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to %s: %s", user.Email, err)
+	}
+
+	if s.requireEmailVerification {
+		return user, "", "", nil
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user, client)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -68,17 +123,68 @@ func (s *AuthService) Register(registerRequest dto.RegisterRequest) (*model.User
 	return user, accessToken, refreshToken, nil
 }
 
-func (s *AuthService) Login(loginRequest dto.LoginRequest) (*model.User, string, string, error) {
-	user, err := s.userRepository.FindByEmail(loginRequest.Email)
+func (s *AuthService) Login(loginRequest dto.LoginRequest, client dto.ClientInfo) (*model.User, string, string, error) {
+	// email is the field older clients send, it is read as an identifier
+	identifier := strings.TrimSpace(loginRequest.Identifier)
+	if identifier == "" {
+		identifier = strings.TrimSpace(loginRequest.Email)
+	}
+	if identifier == "" {
+		return nil, "", "", errors.New("identifier is required")
+	}
+
+	// Unknown accounts still pay for a hash so that the response time does
+	// not tell them apart from a wrong password
+	user, err := s.userRepository.FindByUserNameOrEmail(identifier, normalizeEmail(identifier))
+	if err != nil {
+		s.checkDummyPassword(loginRequest.Password)
+		return nil, "", "", errors.New("invalid credentials")
+	}
+
+	// A throttled or locked account answers like a wrong password, and takes
+	// as long, so that attempts cannot tell it apart from an unknown one
+	now := time.Now()
+	attempts, reserved, err := s.reserveLoginAttempt(user, now)
 	if err != nil {
 		return nil, "", "", err
 	}
+	if !reserved {
+		s.checkDummyPassword(loginRequest.Password)
+		return nil, "", "", errors.New("invalid credentials")
+	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginRequest.Password)); err != nil {
+	if !s.checkPassword(user, loginRequest.Password) {
+		s.lockIfMaxAttempts(user, attempts, now)
 		return nil, "", "", errors.New("invalid credentials")
 	}
 
-	accessToken, refreshToken, err := s.generateTokens(user)
+	if attempts > 0 {
+		if err := s.userRepository.ClearFailedLogins(user.ID); err != nil {
+			log.Printf("Failed to clear the failed logins of user %s: %v", user.UUID, err)
+		}
+	}
+
+	if s.passwordHasher.NeedsRehash(user.Password, user.PasswordPepperVersion) {
+		s.rehashPassword(user, loginRequest.Password)
+	}
+
+	if s.requireEmailVerification && !user.EmailVerified {
+		return nil, "", "", errors.New("email not verified")
+	}
+
+	// Logging in during the grace period restores a deleted account
+	if user.DeletionRequestedAt != nil {
+		if time.Now().After(user.DeletionRequestedAt.Add(s.deletionGracePeriod)) {
+			return nil, "", "", errors.New("invalid credentials")
+		}
+		if err := s.userRepository.CancelDeletion(user.ID); err != nil {
+			return nil, "", "", err
+		}
+		user.DeletionRequestedAt = nil
+		log.Printf("Restored account of user %s", user.UUID)
+	}
+
+	accessToken, refreshToken, err := s.generateTokens(user, client)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -86,59 +192,172 @@ func (s *AuthService) Login(loginRequest dto.LoginRequest) (*model.User, string,
 	return user, accessToken, refreshToken, nil
 }
 
-func (s *AuthService) Logout(tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrInvalidKey
+func (s *AuthService) RefreshToken(refreshToken string, client dto.ClientInfo) (*model.User, string, string, error) {
+	claims, err := s.tokenService.ParseToken(refreshToken, RefreshTokenType)
+	if err != nil {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+
+	if s.blacklistRepo.IsBlacklisted(claims["jti"].(string)) {
+		return nil, "", "", errors.New("token is blacklisted")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+	if HasLegacySubject(claims) {
+		return nil, "", "", errors.New("token format is no longer supported, please log in again")
+	}
+
+	storedToken, err := s.refreshTokenRepo.FindByJTI(claims["jti"].(string))
+	if err != nil || storedToken.RevokedAt != nil {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+
+	rotated, err := s.refreshTokenRepo.MarkRotated(storedToken.JTI)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	session, err := s.sessionRepo.FindByID(storedToken.FamilyID)
+	if err != nil {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+
+	// A refresh token that was already rotated is being replayed, which means
+	// it may have been stolen: revoke every token descended from the same login.
+	if !rotated {
+		log.Printf("Refresh token reuse detected for user %s, revoking token family %s", userID, storedToken.FamilyID)
+		if session.RevokedAt == nil {
+			if err := s.revoker.revokeSession(session); err != nil {
+				return nil, "", "", err
+			}
 		}
-		return []byte(s.jwtSecret), nil
-	})
+		return nil, "", "", errors.New("refresh token reuse detected")
+	}
+
+	if session.RevokedAt != nil {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if IssuedBeforeCutoff(user, claims) {
+		return nil, "", "", errors.New("invalid refresh token")
+	}
+
+	if err := s.sessionRepo.Touch(session.ID, client.UserAgent, client.IPAddress); err != nil {
+		return nil, "", "", err
+	}
+
+	accessToken, newRefreshToken, err := s.issueTokens(user, session)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return user, accessToken, newRefreshToken, nil
+}
 
+// Logout blacklists the access token and ends its session. When the client
+// also presents its refresh token, that token's session is ended as well so it
+// can no longer be rotated.
+func (s *AuthService) Logout(tokenString, refreshToken string) error {
+	claims, err := s.tokenService.ParseToken(tokenString, AccessTokenType)
 	if err != nil {
 		return err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		expiry := time.Unix(int64(claims["exp"].(float64)), 0)
-		return s.blacklistRepo.Add(tokenString, expiry)
+	if refreshToken != "" {
+		if err := s.revokeRefreshToken(refreshToken, claims["sub"].(string)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.blacklistRepo.Add(claims["jti"].(string), ExpiryFromClaims(claims)); err != nil {
+		return err
 	}
 
-	return errors.New("invalid token")
+	if sessionID, ok := claims["sid"].(string); ok {
+		session, err := s.sessionRepo.FindByID(sessionID)
+		if err == nil && session.RevokedAt == nil {
+			return s.revoker.revokeSession(session)
+		}
+	}
+
+	return nil
 }
 
-func (s *AuthService) GetUserProfile(email string) (*model.User, error) {
-	user, err := s.userRepository.FindByEmail(email)
+func (s *AuthService) ListSessions(userID string) ([]model.Session, error) {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.sessionRepo.FindActiveByUserID(user.ID)
+}
+
+func (s *AuthService) RevokeSession(userID, sessionID string) error {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != user.ID || session.RevokedAt != nil {
+		return errors.New("session not found")
+	}
+
+	return s.revoker.revokeSession(session)
+}
+
+func (s *AuthService) RevokeAllSessions(userID string) error {
+	sessions, err := s.ListSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		if err := s.revoker.revokeSession(&sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) GetUserProfile(userID string) (*model.User, error) {
+	user, err := s.userRepository.FindByUUID(userID)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *AuthService) ForgotPassword(email string) (string, error) {
-	user, err := s.userRepository.FindByEmail(email)
+// ForgotPassword mails a password reset link to the user. The token travels
+// only by email, which is what lets a reset lift a lockout. Unknown addresses
+// are ignored without an error so that callers cannot tell which accounts
+// exist.
+func (s *AuthService) ForgotPassword(email string) error {
+	user, err := s.userRepository.FindByEmail(normalizeEmail(email))
 	if err != nil {
-		return "", errors.New("user not found")
+		return nil
 	}
 
-	token := make([]byte, 32)
-	if _, err := rand.Read(token); err != nil {
-		return "", err
+	resetToken, err := generateOneTimeToken()
+	if err != nil {
+		return err
 	}
-	resetToken := hex.EncodeToString(token)
 
 	// Store the token :3
 	expiry := time.Now().Add(1 * time.Hour)
 	if err := s.userRepository.StorePasswordResetToken(user.Email, resetToken, expiry); err != nil {
-		return "", err
-	}
-	// Send the token via email
-	if err := s.emailService.SendPasswordResetEmail(user.Email, resetToken); err != nil {
-		return "", err
+		return err
 	}
 
-	log.Printf("Password reset token for %s: %s", user.Email, resetToken)
-
-	return resetToken, nil
+	// Send the token via email
+	return s.emailService.SendPasswordResetEmail(user.Email, resetToken)
 }
 
 func (s *AuthService) ResetPassword(resetPasswordRequest dto.ResetPasswordRequest) error {
@@ -147,12 +366,20 @@ func (s *AuthService) ResetPassword(resetPasswordRequest dto.ResetPasswordReques
 		return errors.New("invalid or expired reset token")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(resetPasswordRequest.NewPassword), bcrypt.DefaultCost)
+	user, err := s.userRepository.FindByEmail(email)
 	if err != nil {
 		return err
 	}
 
-	if err := s.userRepository.UpdatePassword(email, string(hashedPassword)); err != nil {
+	if err := s.passwordPolicy.Validate(resetPasswordRequest.NewPassword, user.Name, user.Email); err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordHistory(user, resetPasswordRequest.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(user, resetPasswordRequest.NewPassword); err != nil {
 		return err
 	}
 
@@ -160,69 +387,547 @@ func (s *AuthService) ResetPassword(resetPasswordRequest dto.ResetPasswordReques
 		return err
 	}
 
-	return nil
+	// Receiving the reset link proves the account is the user's, lift any lockout
+	if err := s.userRepository.ClearFailedLogins(user.ID); err != nil {
+		return err
+	}
+
+	return s.revoker.revokeAllTokens(user, "")
 }
 
-// --- Private Methods ---
+// ChangePassword replaces the password of a logged-in user after checking the
+// current one. Every token issued so far is revoked and the current session
+// receives a new token pair so that only the caller stays signed in.
+func (s *AuthService) ChangePassword(userID, sessionID string, changePasswordRequest dto.ChangePasswordRequest) (string, string, error) {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !s.checkPassword(user, changePasswordRequest.CurrentPassword) {
+		return "", "", errors.New("invalid current password")
+	}
+
+	if changePasswordRequest.NewPassword == changePasswordRequest.CurrentPassword {
+		return "", "", errors.New("new password must be different from the current password")
+	}
+
+	if err := s.passwordPolicy.Validate(changePasswordRequest.NewPassword, user.Name, user.Email); err != nil {
+		return "", "", err
+	}
+
+	if err := s.checkPasswordHistory(user, changePasswordRequest.NewPassword); err != nil {
+		return "", "", err
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != user.ID || session.RevokedAt != nil {
+		return "", "", errors.New("session not found")
+	}
+
+	if err := s.setPassword(user, changePasswordRequest.NewPassword); err != nil {
+		return "", "", err
+	}
+
+	if err := s.revoker.revokeAllTokens(user, session.ID); err != nil {
+		return "", "", err
+	}
+
+	accessToken, refreshToken, err := s.issueTokens(user, session)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.emailService.SendPasswordChangedEmail(user.Email); err != nil {
+		log.Printf("Failed to send password change notification to %s: %s", user.Email, err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// RequestEmailChange starts moving the account to a new address by mailing a
+// confirmation link to it. Nothing changes until the link is confirmed.
+func (s *AuthService) RequestEmailChange(userID string, changeEmailRequest dto.ChangeEmailRequest) error {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !s.checkPassword(user, changeEmailRequest.Password) {
+		return errors.New("invalid current password")
+	}
+
+	newEmail := normalizeEmail(changeEmailRequest.NewEmail)
+	if newEmail == user.Email {
+		return errors.New("new email must be different from the current email")
+	}
+
+	if existingUser, err := s.userRepository.FindByEmail(newEmail); err == nil && existingUser != nil {
+		return errors.New("email already in use")
+	}
+
+	token, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+	undoToken, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	change := &model.EmailChange{
+		Token:     token,
+		UndoToken: undoToken,
+		UserID:    user.ID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		Expiry:    time.Now().Add(s.emailChangeTokenExpiry),
+	}
+	if err := s.userRepository.StoreEmailChange(change); err != nil {
+		return err
+	}
+
+	return s.emailService.SendEmailChangeConfirmation(change.NewEmail, token)
+}
+
+// ConfirmEmailChange applies a pending email change. The address is also used
+// to recover the account, so every other session is signed out and the current
+// one gets a new token pair. The old address is told about the change and can
+// undo it.
+func (s *AuthService) ConfirmEmailChange(userID, sessionID, token string) (*model.User, string, string, error) {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	change, err := s.userRepository.FindEmailChangeByToken(token)
+	if err != nil || change.UserID != user.ID || change.OldEmail != user.Email || change.ConfirmedAt != nil || time.Now().After(change.Expiry) {
+		return nil, "", "", errors.New("invalid or expired email change token")
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != user.ID || session.RevokedAt != nil {
+		return nil, "", "", errors.New("session not found")
+	}
+
+	if existingUser, err := s.userRepository.FindByEmail(change.NewEmail); err == nil && existingUser != nil {
+		return nil, "", "", errors.New("email already in use")
+	}
+
+	if err := s.userRepository.ChangeEmail(user.ID, change.OldEmail, change.NewEmail); err != nil {
+		return nil, "", "", err
+	}
+
+	now := time.Now()
+	undoExpiry := now.Add(s.emailChangeUndoExpiry)
+	change.ConfirmedAt = &now
+	change.UndoExpiry = &undoExpiry
+	if err := s.userRepository.SaveEmailChange(change); err != nil {
+		return nil, "", "", err
+	}
+
+	user.Email = change.NewEmail
+	user.EmailVerified = true
+	if err := s.revoker.revokeAllTokens(user, session.ID); err != nil {
+		return nil, "", "", err
+	}
+
+	accessToken, refreshToken, err := s.issueTokens(user, session)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := s.emailService.SendEmailChangedNotice(change.OldEmail, change.NewEmail, change.UndoToken); err != nil {
+		log.Printf("Failed to send email change notice to %s: %s", change.OldEmail, err)
+	}
+
+	return user, accessToken, refreshToken, nil
+}
+
+// UndoEmailChange restores the address a confirmed change replaced and signs
+// the account out everywhere, in case the change was made by someone else.
+func (s *AuthService) UndoEmailChange(undoToken string) error {
+	change, err := s.userRepository.FindEmailChangeByUndoToken(undoToken)
+	if err != nil || change.ConfirmedAt == nil || change.UndoneAt != nil || time.Now().After(*change.UndoExpiry) {
+		return errors.New("invalid or expired undo token")
+	}
+
+	user, err := s.userRepository.FindByID(change.UserID)
+	if err != nil || user.Email != change.NewEmail {
+		return errors.New("invalid or expired undo token")
+	}
+
+	if existingUser, err := s.userRepository.FindByEmail(change.OldEmail); err == nil && existingUser != nil {
+		return errors.New("email already in use")
+	}
+
+	if err := s.userRepository.ChangeEmail(user.ID, change.NewEmail, change.OldEmail); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	change.UndoneAt = &now
+	if err := s.userRepository.SaveEmailChange(change); err != nil {
+		return err
+	}
+
+	user.Email = change.OldEmail
+	return s.revoker.revokeAllTokens(user, "")
+}
+
+// DeleteAccount signs the user out everywhere and schedules the account for
+// deletion once the grace period has passed. It returns when the account will
+// be erased.
+func (s *AuthService) DeleteAccount(userID string, deleteAccountRequest dto.DeleteAccountRequest) (time.Time, error) {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !s.checkPassword(user, deleteAccountRequest.Password) {
+		return time.Time{}, errors.New("invalid current password")
+	}
+
+	now := time.Now()
+	if err := s.userRepository.ScheduleDeletion(user.ID, now); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.revoker.revokeAllTokens(user, ""); err != nil {
+		return time.Time{}, err
+	}
 
-func (s *AuthService) generateTokens(user *model.User) (string, string, error) {
-	accessTokenExpiry := time.Now().Add(viper.GetDuration("jwt.access_token_expiry"))
-	refreshTokenExpiry := time.Now().Add(viper.GetDuration("jwt.refresh_token_expiry"))
+	deletionDate := now.Add(s.deletionGracePeriod)
+	if err := s.emailService.SendAccountDeletionEmail(user.Email, deletionDate); err != nil {
+		log.Printf("Failed to send account deletion notice to %s: %s", user.Email, err)
+	}
+
+	return deletionDate, nil
+}
 
-	accessTokenClaims := jwt.MapClaims{
-		"sub": user.Email,
-		"exp": accessTokenExpiry.Unix(),
+func (s *AuthService) VerifyEmail(token string) error {
+	email, err := s.userRepository.FindEmailByVerificationToken(token)
+	if err != nil {
+		return errors.New("invalid or expired verification token")
 	}
 
-	refreshTokenClaims := jwt.MapClaims{
-		"sub": user.Email,
-		"exp": refreshTokenExpiry.Unix(),
+	if err := s.userRepository.MarkEmailVerified(email); err != nil {
+		return err
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
+	return s.userRepository.InvalidateVerificationToken(token)
+}
+
+// ResendVerification mails a new verification link to an unverified user.
+// Unknown and already verified addresses are ignored without an error so that
+// callers cannot tell which accounts exist.
+func (s *AuthService) ResendVerification(email string) error {
+	user, err := s.userRepository.FindByEmail(normalizeEmail(email))
+	if err != nil || user.EmailVerified {
+		return nil
+	}
 
-	accessTokenString, err := accessToken.SignedString([]byte(s.jwtSecret))
+	return s.sendVerificationEmail(user)
+}
+
+// UnlockAccount lifts the lockout of the account the unlock token was mailed to.
+func (s *AuthService) UnlockAccount(token string) error {
+	userID, err := s.userRepository.FindUserIDByUnlockToken(token)
+	if err != nil {
+		return errors.New("invalid or expired unlock token")
+	}
+
+	return s.userRepository.ClearFailedLogins(userID)
+}
+
+// --- Private Methods ---
+
+// generateTokens starts a new session and issues its first token pair.
+func (s *AuthService) generateTokens(user *model.User, client dto.ClientInfo) (string, string, error) {
+	sessionID, err := generateTokenID()
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshTokenString, err := refreshToken.SignedString([]byte(s.jwtSecret))
+	session := &model.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		LastUsedAt: time.Now(),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", "", err
+	}
+
+	return s.issueTokens(user, session)
+}
+
+func (s *AuthService) issueTokens(user *model.User, session *model.Session) (string, string, error) {
+	accessToken, accessClaims, err := s.tokenService.GenerateToken(user.UUID, session.ID, AccessTokenType)
 	if err != nil {
 		return "", "", err
 	}
 
-	return accessTokenString, refreshTokenString, nil
-}
-
-// func isPasswordValid(password string) error {
-// 	if len(password) < minPasswordLength {
-// 		return errors.New("password must be at least 8 characters long")
-// 	}
-
-// 	var (
-// 		hasUpper   bool
-// 		hasLower   bool
-// 		hasNumber  bool
-// 		hasSpecial bool
-// 	)
-
-// 	for _, char := range password {
-// 		switch {
-// 		case unicode.IsUpper(char):
-// 			hasUpper = true
-// 		case unicode.IsLower(char):
-// 			hasLower = true
-// 		case unicode.IsNumber(char):
-// 			hasNumber = true
-// 		case unicode.IsPunct(char) || unicode.IsSymbol(char):
-// 			hasSpecial = true
-// 		}
-// 	}
-
-// 	if !hasUpper || !hasLower || !hasNumber || !hasSpecial {
-// 		return errors.New("password must contain at least one uppercase letter, one lowercase letter, one number, and one special character")
-// 	}
-
-// 	return nil
-// }
+	refreshToken, refreshClaims, err := s.tokenService.GenerateToken(user.UUID, session.ID, RefreshTokenType)
+	if err != nil {
+		return "", "", err
+	}
+
+	storedToken := &model.RefreshToken{
+		JTI:      refreshClaims["jti"].(string),
+		FamilyID: session.ID,
+		Subject:  user.UUID,
+		Expiry:   ExpiryFromClaims(refreshClaims),
+	}
+	if err := s.refreshTokenRepo.Create(storedToken); err != nil {
+		return "", "", err
+	}
+
+	if err := s.sessionRepo.UpdateAccessToken(session.ID, accessClaims["jti"].(string), ExpiryFromClaims(accessClaims)); err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// sendVerificationEmail replaces any pending verification token of the user
+// with a new one and mails it.
+func (s *AuthService) sendVerificationEmail(user *model.User) error {
+	token, err := generateOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Now().Add(s.verificationTokenExpiry)
+	if err := s.userRepository.StoreEmailVerificationToken(user.Email, token, expiry); err != nil {
+		return err
+	}
+
+	return s.emailService.SendVerificationEmail(user.Email, token)
+}
+
+func (s *AuthService) revokeRefreshToken(refreshToken, subject string) error {
+	claims, err := s.tokenService.ParseToken(refreshToken, RefreshTokenType)
+	if err != nil || claims["sub"] != subject {
+		return errors.New("invalid refresh token")
+	}
+
+	storedToken, err := s.refreshTokenRepo.FindByJTI(claims["jti"].(string))
+	if err != nil {
+		return errors.New("invalid refresh token")
+	}
+
+	session, err := s.sessionRepo.FindByID(storedToken.FamilyID)
+	if err != nil {
+		return s.refreshTokenRepo.RevokeFamily(storedToken.FamilyID)
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+	return s.revoker.revokeSession(session)
+}
+
+func generateOneTimeToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// checkPassword reports whether password matches the stored hash of the user.
+func (s *AuthService) checkPassword(user *model.User, password string) bool {
+	ok, err := s.passwordHasher.Verify(user.Password, user.PasswordPepperVersion, password)
+	if err != nil {
+		log.Printf("Failed to verify the password of user %s: %v", user.UUID, err)
+	}
+	return ok
+}
+
+// loginDelay returns how long an account must wait after its last failed login
+// before the password is checked again. The first failures are free, the next
+// ones double the delay up to account_lockout.max_delay, and reaching
+// account_lockout.max_attempts locks the account for account_lockout.duration.
+func (s *AuthService) loginDelay(failures int) time.Duration {
+	if s.lockoutMaxAttempts <= 0 {
+		return 0
+	}
+	if failures >= s.lockoutMaxAttempts {
+		return s.lockoutDuration
+	}
+	if failures <= s.lockoutFreeAttempts || s.lockoutBaseDelay <= 0 {
+		return 0
+	}
+
+	delay := s.lockoutBaseDelay
+	for i := s.lockoutFreeAttempts + 1; i < failures && delay < s.lockoutMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.lockoutMaxDelay {
+		delay = s.lockoutMaxDelay
+	}
+	return delay
+}
+
+// reserveLoginAttempt counts the attempt as a failure before the password is
+// checked so that concurrent attempts cannot get around the lockout, and
+// returns the failures counted so far. It reports false when the account is
+// locked or another attempt reserved it first. Failures older than the lockout
+// duration are forgotten.
+func (s *AuthService) reserveLoginAttempt(user *model.User, now time.Time) (int, bool, error) {
+	if s.lockoutMaxAttempts <= 0 {
+		return 0, true, nil
+	}
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return 0, false, nil
+	}
+
+	attempts := user.FailedLoginAttempts + 1
+	if user.LastFailedLoginAt == nil || user.LastFailedLoginAt.Before(now.Add(-s.lockoutDuration)) {
+		attempts = 1
+	}
+
+	var lockedUntil *time.Time
+	if delay := s.loginDelay(attempts); delay > 0 {
+		until := now.Add(delay)
+		lockedUntil = &until
+	}
+
+	reserved, err := s.userRepository.ReserveLoginAttempt(user, attempts, now, lockedUntil)
+	if err != nil {
+		return 0, false, err
+	}
+	return attempts, reserved, nil
+}
+
+// lockIfMaxAttempts mails an unlock link when the failed attempt is the one
+// that locked the account. Errors are logged only, the caller answers with
+// invalid credentials anyway.
+func (s *AuthService) lockIfMaxAttempts(user *model.User, attempts int, now time.Time) {
+	if s.lockoutMaxAttempts <= 0 || attempts != s.lockoutMaxAttempts {
+		return
+	}
+
+	log.Printf("Locked account of user %s after %d failed logins", user.UUID, attempts)
+
+	token, err := generateOneTimeToken()
+	if err != nil {
+		log.Printf("Failed to generate an unlock token for user %s: %v", user.UUID, err)
+		return
+	}
+
+	lockedUntil := now.Add(s.lockoutDuration)
+	if err := s.userRepository.StoreUnlockToken(user.ID, token, lockedUntil); err != nil {
+		log.Printf("Failed to store the unlock token of user %s: %v", user.UUID, err)
+		return
+	}
+
+	if err := s.emailService.SendAccountLockedEmail(user.Email, token, lockedUntil); err != nil {
+		log.Printf("Failed to send the unlock email to %s: %v", user.Email, err)
+	}
+}
+
+// checkDummyPassword verifies the password against a throwaway hash made with
+// the current parameters, so that refusing a login costs as much as checking
+// a real password.
+func (s *AuthService) checkDummyPassword(password string) {
+	s.dummyHashOnce.Do(func() {
+		hash, pepperVersion, err := s.passwordHasher.Hash("dummy password used to equalise login timing")
+		if err != nil {
+			log.Printf("Failed to hash the dummy password: %v", err)
+			return
+		}
+		s.dummyHash = hash
+		s.dummyPepperVersion = pepperVersion
+	})
+
+	if s.dummyHash != "" {
+		s.passwordHasher.Verify(s.dummyHash, s.dummyPepperVersion, password)
+	}
+}
+
+// checkPasswordHistory refuses the current password of the user and the
+// previous ones kept in the history, up to password_policy.history_size
+// passwords in total. A size of 0 disables the check.
+func (s *AuthService) checkPasswordHistory(user *model.User, password string) error {
+	if s.passwordHistorySize <= 0 {
+		return nil
+	}
+
+	reused := s.checkPassword(user, password)
+	if !reused && s.passwordHistorySize > 1 {
+		history, err := s.userRepository.FindPasswordHistory(user.ID, s.passwordHistorySize-1)
+		if err != nil {
+			return err
+		}
+		for _, previous := range history {
+			ok, err := s.passwordHasher.Verify(previous.Password, previous.PasswordPepperVersion, password)
+			if err != nil {
+				log.Printf("Failed to verify a previous password of user %s: %v", user.UUID, err)
+			}
+			if ok {
+				reused = true
+				break
+			}
+		}
+	}
+
+	if reused {
+		return &PasswordPolicyError{Violations: []dto.PasswordRuleViolation{{
+			Rule:    "history",
+			Message: fmt.Sprintf("password must not be one of your last %d passwords", s.passwordHistorySize),
+		}}}
+	}
+	return nil
+}
+
+// setPassword stores a new password for the user and moves the current one to
+// the password history, which keeps password_policy.history_size - 1 entries.
+func (s *AuthService) setPassword(user *model.User, password string) error {
+	hashedPassword, pepperVersion, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if s.passwordHistorySize > 1 {
+		previous := &model.PasswordHistory{
+			UserID:                user.ID,
+			Password:              user.Password,
+			PasswordPepperVersion: user.PasswordPepperVersion,
+		}
+		if err := s.userRepository.AddPasswordHistory(previous, s.passwordHistorySize-1); err != nil {
+			return err
+		}
+	}
+
+	if err := s.userRepository.UpdatePassword(user.Email, hashedPassword, pepperVersion); err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	user.PasswordPepperVersion = pepperVersion
+	return nil
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm or parameters
+// once the password is known to be right. Failing to do so is not fatal, the
+// next login tries again.
+func (s *AuthService) rehashPassword(user *model.User, password string) {
+	hashedPassword, pepperVersion, err := s.passwordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash the password of user %s: %v", user.UUID, err)
+		return
+	}
+
+	if err := s.userRepository.UpdatePassword(user.Email, hashedPassword, pepperVersion); err != nil {
+		log.Printf("Failed to rehash the password of user %s: %v", user.UUID, err)
+		return
+	}
+	user.Password = hashedPassword
+	user.PasswordPepperVersion = pepperVersion
+}
+
+// normalizeEmail returns the form emails are stored and looked up in.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// BreachedPasswordChecker tells whether a password is known to have leaked.
+// The offline index is the only implementation for now, a checker querying
+// the k-anonymity range API of Have I Been Pwned can be added behind it.
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// The index file starts with a magic header and a fan-out table giving, for
+// every value of the first two bytes of a SHA-1 hash, the number of records
+// up to and including that bucket. The sorted records follow, each holding the
+// next breachedIndexRecordSize bytes of a hash. Truncating hashes to 80 bits
+// keeps the file under half the size of the raw hashes while false positives
+// stay negligible.
+const (
+	breachedIndexMagic      = "HIBPIDX1"
+	breachedIndexBuckets    = 1 << 16
+	breachedIndexRecordSize = 8
+	breachedIndexHeaderSize = len(breachedIndexMagic) + breachedIndexBuckets*8
+)
+
+// BreachedPasswordIndex checks passwords against an index file built by
+// BuildBreachedPasswordIndex. Only the fan-out table is kept in memory.
+type BreachedPasswordIndex struct {
+	file   *os.File
+	fanOut []uint64
+}
+
+// NewBreachedPasswordChecker opens the index configured in
+// breached_passwords.index_path and returns nil when none is configured.
+func NewBreachedPasswordChecker() (BreachedPasswordChecker, error) {
+	indexPath := viper.GetString("breached_passwords.index_path")
+	if indexPath == "" {
+		return nil, nil
+	}
+
+	index, err := OpenBreachedPasswordIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func OpenBreachedPasswordIndex(path string) (*BreachedPasswordIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, breachedIndexHeaderSize)
+	if _, err := io.ReadFull(file, header); err != nil || string(header[:len(breachedIndexMagic)]) != breachedIndexMagic {
+		file.Close()
+		return nil, errors.New("not a breached password index")
+	}
+
+	fanOut := make([]uint64, breachedIndexBuckets)
+	for i := range fanOut {
+		fanOut[i] = binary.BigEndian.Uint64(header[len(breachedIndexMagic)+i*8:])
+	}
+
+	return &BreachedPasswordIndex{file: file, fanOut: fanOut}, nil
+}
+
+func (i *BreachedPasswordIndex) IsBreached(password string) (bool, error) {
+	hash := sha1.Sum([]byte(password))
+	bucket := int(binary.BigEndian.Uint16(hash[:2]))
+
+	var start uint64
+	if bucket > 0 {
+		start = i.fanOut[bucket-1]
+	}
+	end := i.fanOut[bucket]
+	if start == end {
+		return false, nil
+	}
+
+	records := make([]byte, (end-start)*breachedIndexRecordSize)
+	if _, err := i.file.ReadAt(records, int64(breachedIndexHeaderSize)+int64(start)*breachedIndexRecordSize); err != nil {
+		return false, err
+	}
+
+	key := hash[2 : 2+breachedIndexRecordSize]
+	count := int(end - start)
+	found := sort.Search(count, func(n int) bool {
+		return bytes.Compare(records[n*breachedIndexRecordSize:(n+1)*breachedIndexRecordSize], key) >= 0
+	})
+	return found < count && bytes.Equal(records[found*breachedIndexRecordSize:(found+1)*breachedIndexRecordSize], key), nil
+}
+
+func (i *BreachedPasswordIndex) Close() error {
+	return i.file.Close()
+}
+
+// BuildBreachedPasswordIndex converts a Have I Been Pwned SHA-1 file, ordered
+// by hash with one "HASH:COUNT" line per password, into an index file.
+// Passwords seen fewer than minCount times are left out. It returns the number
+// of hashes written.
+func BuildBreachedPasswordIndex(sourcePath, indexPath string, minCount int) (int, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	tmp := indexPath + ".tmp"
+	index, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer index.Close()
+
+	if _, err := index.Seek(int64(breachedIndexHeaderSize), io.SeekStart); err != nil {
+		return 0, err
+	}
+	writer := bufio.NewWriter(index)
+
+	fanOut := make([]uint64, breachedIndexBuckets)
+	var previous []byte
+	var written uint64
+
+	scanner := bufio.NewScanner(source)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hashHex, countText, hasCount := strings.Cut(text, ":")
+		hash, err := hex.DecodeString(hashHex)
+		if err != nil || len(hash) != sha1.Size {
+			return 0, fmt.Errorf("line %d: invalid SHA-1 hash", line)
+		}
+		if hasCount {
+			count, err := strconv.Atoi(countText)
+			if err != nil {
+				return 0, fmt.Errorf("line %d: invalid count", line)
+			}
+			if count < minCount {
+				continue
+			}
+		}
+
+		record := hash[:2+breachedIndexRecordSize]
+		if previous != nil {
+			switch bytes.Compare(record, previous) {
+			case -1:
+				return 0, fmt.Errorf("line %d: source file is not ordered by hash", line)
+			case 0:
+				// Hashes that only differ after the truncated bytes share a record
+				continue
+			}
+		}
+		previous = record
+
+		if _, err := writer.Write(record[2:]); err != nil {
+			return 0, err
+		}
+		fanOut[binary.BigEndian.Uint16(record[:2])]++
+		written++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+
+	header := make([]byte, breachedIndexHeaderSize)
+	copy(header, breachedIndexMagic)
+	var total uint64
+	for bucket, count := range fanOut {
+		total += count
+		binary.BigEndian.PutUint64(header[len(breachedIndexMagic)+bucket*8:], total)
+	}
+	if _, err := index.WriteAt(header, 0); err != nil {
+		return 0, err
+	}
+
+	if err := index.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, indexPath); err != nil {
+		return 0, err
+	}
+	return int(written), nil
+}
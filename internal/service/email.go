@@ -3,12 +3,19 @@ package service
 import (
 	"fmt"
 	"net/smtp"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type EmailService interface {
 	SendPasswordResetEmail(to, token string) error
+	SendVerificationEmail(to, token string) error
+	SendPasswordChangedEmail(to string) error
+	SendEmailChangeConfirmation(to, token string) error
+	SendEmailChangedNotice(to, newEmail, undoToken string) error
+	SendAccountDeletionEmail(to string, deletionDate time.Time) error
+	SendAccountLockedEmail(to, token string, lockedUntil time.Time) error
 }
 
 type emailService struct {
@@ -30,6 +37,108 @@ func NewEmailService() EmailService {
 }
 
 func (s *emailService) SendPasswordResetEmail(to, token string) error {
+	resetURL := appURL("reset-password", token)
+	body := fmt.Sprintf(
+		"WARNING: You just have to add the token to the field in reset-password with your new password on Swagger\r\n"+
+			"\r\n"+
+			"Hello,\r\n\r\n"+
+			"We received a request to reset your password. Please click the link below to reset your password:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"If you did not request a password reset, please ignore this email.\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		resetURL)
+
+	return s.send(to, "Fake Password Reset", body)
+}
+
+func (s *emailService) SendVerificationEmail(to, token string) error {
+	verifyURL := appURL("verify-email", token)
+	body := fmt.Sprintf(
+		"WARNING: You just have to add the token to the field in verify-email on Swagger\r\n"+
+			"\r\n"+
+			"Hello,\r\n\r\n"+
+			"Please click the link below to confirm your email address:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"If you did not create an account, please ignore this email.\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		verifyURL)
+
+	return s.send(to, "Verify your email address", body)
+}
+
+func (s *emailService) SendPasswordChangedEmail(to string) error {
+	body := fmt.Sprintf(
+		"Hello,\r\n\r\n"+
+			"The password of your account was changed and every other device was signed out.\r\n\r\n"+
+			"If you did not make this change, reset your password right away:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		appURL("forgot-password", ""))
+
+	return s.send(to, "Your password was changed", body)
+}
+
+func (s *emailService) SendEmailChangeConfirmation(to, token string) error {
+	confirmURL := appURL("me/email/confirm", token)
+	body := fmt.Sprintf(
+		"Hello,\r\n\r\n"+
+			"Please click the link below while signed in to use this address for your account:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"If you did not ask for this change, please ignore this email.\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		confirmURL)
+
+	return s.send(to, "Confirm your new email address", body)
+}
+
+func (s *emailService) SendEmailChangedNotice(to, newEmail, undoToken string) error {
+	undoURL := appURL("undo-email-change", undoToken)
+	body := fmt.Sprintf(
+		"Hello,\r\n\r\n"+
+			"The email address of your account was changed to %s and every device was signed out.\r\n\r\n"+
+			"If you did not make this change, click the link below to restore this address, then reset your password:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		newEmail, undoURL)
+
+	return s.send(to, "Your email address was changed", body)
+}
+
+func (s *emailService) SendAccountDeletionEmail(to string, deletionDate time.Time) error {
+	body := fmt.Sprintf(
+		"Hello,\r\n\r\n"+
+			"Your account was deleted and every device was signed out.\r\n\r\n"+
+			"Your data will be erased on %s. Until then, logging in again restores your account.\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		deletionDate.UTC().Format("January 2, 2006 at 15:04 UTC"))
+
+	return s.send(to, "Your account was deleted", body)
+}
+
+func (s *emailService) SendAccountLockedEmail(to, token string, lockedUntil time.Time) error {
+	unlockURL := appURL("unlock-account", token)
+	body := fmt.Sprintf(
+		"Hello,\r\n\r\n"+
+			"Your account was locked until %s after too many failed login attempts.\r\n\r\n"+
+			"If it was you, click the link below to unlock it now:\r\n\r\n"+
+			"%s\r\n\r\n"+
+			"If it was not you, someone may be guessing your password. Consider resetting it.\r\n\r\n"+
+			"Thank you,\r\n"+
+			"Your Team",
+		lockedUntil.UTC().Format("January 2, 2006 at 15:04 UTC"), unlockURL)
+
+	return s.send(to, "Your account was locked", body)
+}
+
+// --- Private Methods ---
+
+func (s *emailService) send(to, subject, body string) error {
 	var auth smtp.Auth
 	if s.username != "" && s.password != "" {
 		auth = smtp.PlainAuth("", s.username, s.password, s.host)
@@ -37,29 +146,31 @@ func (s *emailService) SendPasswordResetEmail(to, token string) error {
 		auth = nil
 	}
 
-	host := viper.GetString("APP_HOST")
-	protocol := "http"
-
-	if viper.GetString("APP_ENVIRONMENT") == "production" {
-		protocol = "https"
-	}
-
-	resetURL := fmt.Sprintf("%s://%s/reset-password?token=%s", protocol, host, token)
 	msg := fmt.Appendf(nil,
 		"From: %s\r\n"+
 			"To: %s\r\n"+
-			"Subject: Fake Password Reset\r\n"+
-			"\r\n"+
-			"WARNING: You just have to add the token to the field in reset-password with your new password on Swagger\r\n"+
+			"Subject: %s\r\n"+
 			"\r\n"+
-			"Hello,\r\n\r\n"+
-			"We received a request to reset your password. Please click the link below to reset your password:\r\n\r\n"+
-			"%s\r\n\r\n"+
-			"If you did not request a password reset, please ignore this email.\r\n\r\n"+
-			"Thank you,\r\n"+
-			"Your Team",
-		s.from, to, resetURL)
+			"%s",
+		s.from, to, subject, body)
 
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
 	return smtp.SendMail(addr, auth, s.from, []string{to}, msg)
 }
+
+// appURL builds a link to a page of the application carrying a one-time token,
+// or a plain link when there is no token.
+func appURL(path, token string) string {
+	host := viper.GetString("APP_HOST")
+	protocol := "http"
+
+	if viper.GetString("APP_ENVIRONMENT") == "production" {
+		protocol = "https"
+	}
+
+	url := fmt.Sprintf("%s://%s/%s", protocol, host, path)
+	if token == "" {
+		return url
+	}
+	return url + "?token=" + token
+}
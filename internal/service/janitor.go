@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/spf13/viper"
+)
+
+// Janitor periodically deletes expired tokens, links and old sessions so
+// those tables do not grow forever, and erases deleted accounts once their
+// grace period is over.
+type Janitor struct {
+	blacklistRepo       repository.BlacklistRepository
+	userRepository      repository.UserRepository
+	refreshTokenRepo    repository.RefreshTokenRepository
+	sessionRepo         repository.SessionRepository
+	interval            time.Duration
+	batchSize           int
+	sessionRetention    time.Duration
+	deletionGracePeriod time.Duration
+	stop                chan struct{}
+	done                chan struct{}
+}
+
+// PurgeResult counts the rows removed by a purge.
+type PurgeResult struct {
+	BlacklistedTokens  int64
+	PasswordResets     int64
+	EmailVerifications int64
+	EmailChanges       int64
+	AccountUnlocks     int64
+	RefreshTokens      int64
+	Sessions           int64
+}
+
+func NewJanitor(blacklistRepo repository.BlacklistRepository, userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository) *Janitor {
+	// A session can be refreshed until its refresh token expires, so it is
+	// never removed before that even with a shorter retention.
+	sessionRetention := viper.GetDuration("janitor.session_retention")
+	if refreshTokenExpiry := viper.GetDuration("jwt.refresh_token_expiry"); sessionRetention < refreshTokenExpiry {
+		sessionRetention = refreshTokenExpiry
+	}
+
+	return &Janitor{
+		blacklistRepo:       blacklistRepo,
+		userRepository:      userRepo,
+		refreshTokenRepo:    refreshTokenRepo,
+		sessionRepo:         sessionRepo,
+		interval:            viper.GetDuration("janitor.interval"),
+		batchSize:           viper.GetInt("janitor.batch_size"),
+		sessionRetention:    sessionRetention,
+		deletionGracePeriod: viper.GetDuration("account_deletion.grace_period"),
+	}
+}
+
+// Start runs a purge right away and then every interval in a background
+// goroutine, so that restarting more often than the interval still cleans up.
+// A zero interval disables the janitor.
+func (j *Janitor) Start() {
+	if j.interval <= 0 || j.stop != nil {
+		return
+	}
+
+	j.stop = make(chan struct{})
+	j.done = make(chan struct{})
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.run()
+		for {
+			select {
+			case <-ticker.C:
+				j.run()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop waits for a running purge to finish its current batch and stops the janitor.
+func (j *Janitor) Stop() {
+	if j.stop == nil {
+		return
+	}
+	close(j.stop)
+	<-j.done
+	j.stop = nil
+}
+
+// Purge deletes every expired row and the sessions unused for longer than the
+// retention in batches, and returns how many rows were removed from each table.
+func (j *Janitor) Purge() (PurgeResult, error) {
+	now := time.Now()
+	sessionsBefore := now.Add(-j.sessionRetention)
+
+	var result PurgeResult
+	purges := []struct {
+		table       string
+		deleted     *int64
+		deleteBatch func(limit int) (int64, error)
+	}{
+		{"revoked_tokens", &result.BlacklistedTokens, func(limit int) (int64, error) { return j.blacklistRepo.DeleteExpired(now, limit) }},
+		{"password_resets", &result.PasswordResets, func(limit int) (int64, error) { return j.userRepository.DeleteExpiredResetTokens(now, limit) }},
+		{"email_verifications", &result.EmailVerifications, func(limit int) (int64, error) { return j.userRepository.DeleteExpiredVerificationTokens(now, limit) }},
+		{"email_changes", &result.EmailChanges, func(limit int) (int64, error) { return j.userRepository.DeleteExpiredEmailChanges(now, limit) }},
+		{"account_unlocks", &result.AccountUnlocks, func(limit int) (int64, error) { return j.userRepository.DeleteExpiredUnlockTokens(now, limit) }},
+		{"refresh_tokens", &result.RefreshTokens, func(limit int) (int64, error) { return j.refreshTokenRepo.DeleteExpired(now, limit) }},
+		{"sessions", &result.Sessions, func(limit int) (int64, error) { return j.sessionRepo.DeleteInactive(sessionsBefore, limit) }},
+	}
+
+	// A table that cannot be purged does not hold back the others
+	var errs []error
+	for _, purge := range purges {
+		if j.stopping() {
+			break
+		}
+		deleted, err := j.deleteInBatches(purge.deleteBatch)
+		*purge.deleted = deleted
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purging %s: %w", purge.table, err))
+		}
+	}
+
+	log.Printf(
+		"Janitor removed %d blacklisted tokens, %d password resets, %d email verifications, %d email changes, %d account unlocks, %d refresh tokens and %d sessions",
+		result.BlacklistedTokens, result.PasswordResets, result.EmailVerifications, result.EmailChanges,
+		result.AccountUnlocks, result.RefreshTokens, result.Sessions,
+	)
+	return result, errors.Join(errs...)
+}
+
+// PurgeDeletedAccounts erases the accounts whose grace period is over and
+// returns how many were removed.
+func (j *Janitor) PurgeDeletedAccounts() (int64, error) {
+	before := time.Now().Add(-j.deletionGracePeriod)
+
+	accounts, err := j.deleteInBatches(func(limit int) (int64, error) {
+		return j.userRepository.PurgeDeletedUsers(before, limit)
+	})
+	if err != nil {
+		return accounts, err
+	}
+
+	if accounts > 0 {
+		log.Printf("Janitor erased %d deleted accounts", accounts)
+	}
+	return accounts, nil
+}
+
+// --- Private Methods ---
+
+func (j *Janitor) run() {
+	if _, err := j.Purge(); err != nil {
+		log.Printf("Janitor purge failed: %v", err)
+	}
+	if _, err := j.PurgeDeletedAccounts(); err != nil {
+		log.Printf("Janitor account purge failed: %v", err)
+	}
+}
+
+// stopping reports whether Stop was called while a purge is running.
+func (j *Janitor) stopping() bool {
+	select {
+	case <-j.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *Janitor) deleteInBatches(deleteBatch func(limit int) (int64, error)) (int64, error) {
+	batchSize := j.batchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var total int64
+	for {
+		deleted, err := deleteBatch(batchSize)
+		total += deleted
+		if err != nil || deleted < int64(batchSize) {
+			return total, err
+		}
+
+		if j.stopping() {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultKeyID identifies the key configured directly in config.yaml. Tokens
+	// issued without a kid header are verified with it.
+	defaultKeyID = "default"
+
+	keyRingManifest = "keyring.json"
+
+	keyStatusActive = "active"
+	keyStatusVerify = "verify"
+	// keyStatusNext marks a key that verifies tokens and is published in the
+	// JWKS but does not sign until it is promoted.
+	keyStatusNext = "next"
+)
+
+// keyRing holds the key used to sign new tokens, every key that may still
+// verify tokens issued before the last rotation and the next key, if any.
+type keyRing struct {
+	activeKeyID string
+	keys        map[string]*signingKey
+}
+
+type keyRingFile struct {
+	Keys []keyRingEntry `json:"keys"`
+}
+
+type keyRingEntry struct {
+	ID        string     `json:"kid"`
+	Algorithm string     `json:"algorithm"`
+	File      string     `json:"file"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetireAt  *time.Time `json:"retire_at,omitempty"`
+}
+
+func newSingleKeyRing(key *signingKey) *keyRing {
+	key.id = defaultKeyID
+	return &keyRing{
+		activeKeyID: defaultKeyID,
+		keys:        map[string]*signingKey{defaultKeyID: key},
+	}
+}
+
+// loadKeyRing reads the manifest in dir and loads every key that has not been
+// retired yet.
+func loadKeyRing(dir string) (*keyRing, error) {
+	manifest, err := readKeyRingFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &keyRing{keys: map[string]*signingKey{}}
+	now := time.Now()
+	for _, entry := range manifest.Keys {
+		if entry.RetireAt != nil && entry.RetireAt.Before(now) {
+			continue
+		}
+
+		key, err := loadKeyRingEntry(dir, entry)
+		if err != nil {
+			return nil, fmt.Errorf("loading key %s: %w", entry.ID, err)
+		}
+		ring.keys[entry.ID] = key
+
+		if entry.Status == keyStatusActive {
+			ring.activeKeyID = entry.ID
+		}
+	}
+
+	if ring.activeKeyID == "" {
+		return nil, errors.New("key ring has no active signing key")
+	}
+
+	return ring, nil
+}
+
+func (r *keyRing) active() *signingKey {
+	return r.keys[r.activeKeyID]
+}
+
+// lookup returns the verification key for a kid header.
+func (r *keyRing) lookup(keyID string) (*signingKey, bool) {
+	if keyID == "" {
+		keyID = defaultKeyID
+	}
+	key, ok := r.keys[keyID]
+	return key, ok
+}
+
+// AddSigningKey generates the next signing key in the key ring directory. It
+// only verifies tokens and is published in the JWKS until PromoteSigningKey
+// makes it active, which leaves every instance time to load it first. Keys
+// whose retirement date has passed are removed. When the directory has no
+// manifest yet, the key configured in config.yaml is imported first so that
+// tokens signed with it keep working.
+func AddSigningKey(dir, algorithm string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	manifest, err := readKeyRingFile(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		manifest = &keyRingFile{}
+		entry, err := importConfiguredKey(dir)
+		if err != nil {
+			return "", err
+		}
+		if entry != nil {
+			manifest.Keys = append(manifest.Keys, *entry)
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	manifest.Keys = pruneRetiredKeys(dir, manifest.Keys, now)
+	for _, entry := range manifest.Keys {
+		if entry.Status == keyStatusNext {
+			return "", fmt.Errorf("key %s is already waiting to be promoted", entry.ID)
+		}
+	}
+
+	entry, err := generateKeyRingEntry(dir, algorithm, now)
+	if err != nil {
+		return "", err
+	}
+	entry.Status = keyStatusNext
+	manifest.Keys = append(manifest.Keys, entry)
+
+	if err := writeKeyRingFile(dir, manifest); err != nil {
+		return "", err
+	}
+
+	return entry.ID, nil
+}
+
+// PromoteSigningKey makes the next key added by AddSigningKey the active one.
+// The previous active key stays valid for verification for retireAfter, and
+// keys whose retirement date has passed are removed.
+func PromoteSigningKey(dir string, retireAfter time.Duration) (string, error) {
+	manifest, err := readKeyRingFile(dir)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	retireAt := now.Add(retireAfter)
+	keys := pruneRetiredKeys(dir, manifest.Keys, now)
+
+	promoted := -1
+	for i := range keys {
+		if keys[i].Status == keyStatusNext {
+			promoted = i
+		}
+	}
+	if promoted < 0 {
+		return "", errors.New("key ring has no key waiting to be promoted")
+	}
+
+	for i := range keys {
+		switch {
+		case i == promoted:
+			keys[i].Status = keyStatusActive
+		case keys[i].Status == keyStatusActive:
+			keys[i].Status = keyStatusVerify
+			keys[i].RetireAt = &retireAt
+		}
+	}
+	manifest.Keys = keys
+
+	if err := writeKeyRingFile(dir, manifest); err != nil {
+		return "", err
+	}
+
+	return keys[promoted].ID, nil
+}
+
+// --- Private Methods ---
+
+// pruneRetiredKeys drops the entries whose retirement date has passed and
+// deletes their key files.
+func pruneRetiredKeys(dir string, entries []keyRingEntry, now time.Time) []keyRingEntry {
+	keys := entries[:0]
+	for _, entry := range entries {
+		if entry.RetireAt != nil && entry.RetireAt.Before(now) {
+			os.Remove(filepath.Join(dir, entry.File))
+			continue
+		}
+		keys = append(keys, entry)
+	}
+	return keys
+}
+
+func readKeyRingFile(dir string) (*keyRingFile, error) {
+	data, err := os.ReadFile(filepath.Join(dir, keyRingManifest))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest keyRingFile
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func writeKeyRingFile(dir string, manifest *keyRingFile) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, keyRingManifest+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, keyRingManifest))
+}
+
+func loadKeyRingEntry(dir string, entry keyRingEntry) (*signingKey, error) {
+	path := filepath.Join(dir, entry.File)
+
+	var key *signingKey
+	var err error
+	if entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+		secret, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return nil, readErr
+		}
+		key, err = loadSigningKey(entry.Algorithm, strings.TrimSpace(string(secret)), "")
+	} else {
+		key, err = loadSigningKey(entry.Algorithm, "", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key.id = entry.ID
+	return key, nil
+}
+
+func importConfiguredKey(dir string) (*keyRingEntry, error) {
+	entry := keyRingEntry{
+		ID:        defaultKeyID,
+		Algorithm: viper.GetString("jwt.algorithm"),
+		Status:    keyStatusActive,
+		CreatedAt: time.Now(),
+	}
+
+	var data []byte
+	if entry.Algorithm == "" || entry.Algorithm == jwt.SigningMethodHS256.Alg() {
+		secret := viper.GetString("jwt.secret")
+		if secret == "" {
+			return nil, nil
+		}
+		entry.Algorithm = jwt.SigningMethodHS256.Alg()
+		entry.File = defaultKeyID + ".secret"
+		data = []byte(secret)
+	} else {
+		privateKeyPath := viper.GetString("jwt.private_key_path")
+		if privateKeyPath == "" {
+			return nil, nil
+		}
+		pemBytes, err := os.ReadFile(privateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		entry.File = defaultKeyID + ".pem"
+		data = pemBytes
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, entry.File), data, 0600); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func generateKeyRingEntry(dir, algorithm string, now time.Time) (keyRingEntry, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return keyRingEntry{}, err
+	}
+
+	entry := keyRingEntry{
+		ID:        fmt.Sprintf("%s-%s", now.UTC().Format("20060102150405"), hex.EncodeToString(suffix)),
+		Algorithm: algorithm,
+		Status:    keyStatusActive,
+		CreatedAt: now,
+	}
+
+	if algorithm == "" || algorithm == jwt.SigningMethodHS256.Alg() {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return keyRingEntry{}, err
+		}
+		entry.Algorithm = jwt.SigningMethodHS256.Alg()
+		entry.File = entry.ID + ".secret"
+		return entry, os.WriteFile(filepath.Join(dir, entry.File), []byte(hex.EncodeToString(secret)), 0600)
+	}
+
+	var privateKey interface{}
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return keyRingEntry{}, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+	if err != nil {
+		return keyRingEntry{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return keyRingEntry{}, err
+	}
+	entry.File = entry.ID + ".pem"
+	return entry, writePrivateKeyFile(filepath.Join(dir, entry.File), der)
+}
+
+func writePrivateKeyFile(path string, der []byte) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
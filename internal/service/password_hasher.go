@@ -0,0 +1,211 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+)
+
+// Shorter argon2id salts and keys are refused, a stored hash with an empty key
+// would match any password.
+const (
+	minArgon2idSaltLength = 8
+	minArgon2idKeyLength  = 16
+)
+
+// PasswordHasher hashes passwords and checks them against stored hashes. The
+// pepper version returned with a hash must be stored with it.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of a password with the configured algorithm
+	// and the version of the pepper applied to it.
+	Hash(password string) (string, int, error)
+	// Verify reports whether password matches an encoded hash produced by any
+	// supported algorithm with the given pepper version.
+	Verify(encodedHash string, pepperVersion int, password string) (bool, error)
+	// NeedsRehash reports whether an encoded hash uses another algorithm, weaker
+	// parameters or another pepper than the configured ones.
+	NeedsRehash(encodedHash string, pepperVersion int) bool
+}
+
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// passwordHasher writes argon2id hashes in the PHC string format
+// ($argon2id$v=19$m=65536,t=3,p=2$salt$hash) or bcrypt hashes, and reads both.
+// Passwords are peppered before hashing when a pepper is configured.
+type passwordHasher struct {
+	algorithm  string
+	argon2id   argon2idParams
+	bcryptCost int
+	peppers    *pepperRing
+}
+
+func NewPasswordHasher() (PasswordHasher, error) {
+	hasher := &passwordHasher{
+		algorithm: viper.GetString("password_hashing.algorithm"),
+		argon2id: argon2idParams{
+			memory:      viper.GetUint32("password_hashing.argon2id.memory"),
+			iterations:  viper.GetUint32("password_hashing.argon2id.iterations"),
+			parallelism: uint8(viper.GetUint("password_hashing.argon2id.parallelism")),
+			saltLength:  viper.GetUint32("password_hashing.argon2id.salt_length"),
+			keyLength:   viper.GetUint32("password_hashing.argon2id.key_length"),
+		},
+		bcryptCost: viper.GetInt("password_hashing.bcrypt_cost"),
+	}
+
+	peppers, err := loadPepperRing()
+	if err != nil {
+		return nil, err
+	}
+	hasher.peppers = peppers
+
+	switch hasher.algorithm {
+	case HashAlgorithmArgon2id:
+		params := hasher.argon2id
+		if params.memory == 0 || params.iterations == 0 || params.parallelism == 0 || params.saltLength == 0 || params.keyLength == 0 {
+			return nil, errors.New("argon2id memory, iterations, parallelism, salt length and key length must be set")
+		}
+		if params.saltLength < minArgon2idSaltLength || params.keyLength < minArgon2idKeyLength {
+			return nil, fmt.Errorf("argon2id salt length must be at least %d and key length at least %d", minArgon2idSaltLength, minArgon2idKeyLength)
+		}
+	case HashAlgorithmBcrypt:
+		if hasher.bcryptCost < bcrypt.MinCost || hasher.bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %s", hasher.algorithm)
+	}
+
+	return hasher, nil
+}
+
+func (h *passwordHasher) Hash(password string) (string, int, error) {
+	pepperVersion := h.peppers.currentVersion
+	password, err := h.peppers.apply(pepperVersion, password)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if h.algorithm == HashAlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", 0, err
+		}
+		return string(hash), pepperVersion, nil
+	}
+
+	salt := make([]byte, h.argon2id.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", 0, err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.argon2id.iterations, h.argon2id.memory, h.argon2id.parallelism, h.argon2id.keyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		HashAlgorithmArgon2id,
+		argon2.Version,
+		h.argon2id.memory,
+		h.argon2id.iterations,
+		h.argon2id.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), pepperVersion, nil
+}
+
+func (h *passwordHasher) Verify(encodedHash string, pepperVersion int, password string) (bool, error) {
+	password, err := h.peppers.apply(pepperVersion, password)
+	if err != nil {
+		return false, err
+	}
+
+	if isBcryptHash(encodedHash) {
+		err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+
+	return subtle.ConstantTimeCompare(key, computed) == 1, nil
+}
+
+func (h *passwordHasher) NeedsRehash(encodedHash string, pepperVersion int) bool {
+	if pepperVersion != h.peppers.currentVersion {
+		return true
+	}
+
+	if isBcryptHash(encodedHash) {
+		if h.algorithm != HashAlgorithmBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(encodedHash))
+		return err != nil || cost < h.bcryptCost
+	}
+
+	if h.algorithm != HashAlgorithmArgon2id {
+		return true
+	}
+	params, _, _, err := decodeArgon2idHash(encodedHash)
+	return err != nil || params != h.argon2id
+}
+
+// --- Private Methods ---
+
+func isBcryptHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// decodeArgon2idHash parses a hash in the PHC string format.
+func decodeArgon2idHash(encodedHash string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != HashAlgorithmArgon2id {
+		return params, nil, nil, errors.New("unsupported password hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil ||
+		params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return params, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) < minArgon2idSaltLength {
+		return params, nil, nil, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < minArgon2idKeyLength {
+		return params, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	params.saltLength = uint32(len(salt))
+	params.keyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/spf13/viper"
+)
+
+// bcryptMaxBytes is the length after which bcrypt silently ignores the rest of
+// a password.
+const bcryptMaxBytes = 72
+
+// minUserInputLength keeps very short names from rejecting half the passwords
+// that happen to contain them.
+const minUserInputLength = 3
+
+// PasswordPolicy holds the rules every new password must satisfy.
+type PasswordPolicy struct {
+	minLength        int
+	maxBytes         int
+	requireUpper     bool
+	requireLower     bool
+	requireDigit     bool
+	requireSpecial   bool
+	disallowUserInfo bool
+	minScore         int
+
+	breachedPasswords BreachedPasswordChecker
+}
+
+// PasswordPolicyError lists every rule a password broke.
+type PasswordPolicyError struct {
+	Violations []dto.PasswordRuleViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy"
+}
+
+// NewPasswordPolicy reads the rules from config.yaml. breachedPasswords may be
+// nil when no breached password list is configured.
+func NewPasswordPolicy(breachedPasswords BreachedPasswordChecker) *PasswordPolicy {
+	maxBytes := viper.GetInt("password_policy.max_bytes")
+	if maxBytes <= 0 || (viper.GetString("password_hashing.algorithm") == HashAlgorithmBcrypt && maxBytes > bcryptMaxBytes) {
+		maxBytes = bcryptMaxBytes
+	}
+
+	return &PasswordPolicy{
+		minLength:        viper.GetInt("password_policy.min_length"),
+		maxBytes:         maxBytes,
+		requireUpper:     viper.GetBool("password_policy.require_upper"),
+		requireLower:     viper.GetBool("password_policy.require_lower"),
+		requireDigit:     viper.GetBool("password_policy.require_digit"),
+		requireSpecial:   viper.GetBool("password_policy.require_special"),
+		disallowUserInfo: viper.GetBool("password_policy.disallow_user_info"),
+		minScore:         viper.GetInt("password_policy.min_score"),
+
+		breachedPasswords: breachedPasswords,
+	}
+}
+
+// Validate checks a password against every rule of the policy. userInputs are
+// the name and email of the account, which the password may not contain.
+func (p *PasswordPolicy) Validate(password string, userInputs ...string) error {
+	var violations []dto.PasswordRuleViolation
+	violate := func(rule, message string) {
+		violations = append(violations, dto.PasswordRuleViolation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.minLength {
+		violate("min_length", fmt.Sprintf("password must be at least %d characters long", p.minLength))
+	}
+	if len(password) > p.maxBytes {
+		violate("max_length", fmt.Sprintf("password must be at most %d bytes long", p.maxBytes))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case unicode.IsPunct(char) || unicode.IsSymbol(char) || unicode.IsSpace(char):
+			hasSpecial = true
+		}
+	}
+	if p.requireUpper && !hasUpper {
+		violate("uppercase", "password must contain an uppercase letter")
+	}
+	if p.requireLower && !hasLower {
+		violate("lowercase", "password must contain a lowercase letter")
+	}
+	if p.requireDigit && !hasDigit {
+		violate("digit", "password must contain a digit")
+	}
+	if p.requireSpecial && !hasSpecial {
+		violate("special", "password must contain a special character")
+	}
+
+	if p.disallowUserInfo && containsUserInput(password, userInputs) {
+		violate("user_info", "password must not contain your name or email address")
+	}
+
+	if p.minScore > 0 && PasswordStrength(password) < p.minScore {
+		violate("strength", "password is too easy to guess")
+	}
+
+	// A checker that cannot answer must not lock users out of their accounts
+	if p.breachedPasswords != nil {
+		if breached, err := p.breachedPasswords.IsBreached(password); err != nil {
+			log.Printf("Failed to check the password against breached passwords: %v", err)
+		} else if breached {
+			violate("breached", "password has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// --- Private Methods ---
+
+// containsUserInput reports whether the password contains one of the inputs,
+// a word of one of them, or the local part of an email address among them,
+// ignoring case.
+func containsUserInput(password string, userInputs []string) bool {
+	password = strings.ToLower(password)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		candidates := append([]string{input}, strings.Fields(input)...)
+		if at := strings.LastIndex(input, "@"); at > 0 {
+			candidates = append(candidates, input[:at])
+		}
+
+		for _, candidate := range candidates {
+			if utf8.RuneCountInString(candidate) >= minUserInputLength && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
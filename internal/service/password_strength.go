@@ -0,0 +1,158 @@
+package service
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are the most used passwords and keyboard patterns, most
+// common first. The rank of a match is the number of guesses it costs.
+var commonPasswords = []string{
+	"password", "123456", "12345678", "qwerty", "123456789", "12345", "1234", "111111",
+	"1234567", "dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein",
+	"696969", "shadow", "master", "666666", "qwertyuiop", "123321", "mustang", "1234567890",
+	"michael", "654321", "superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx",
+	"123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+	"2000", "charlie", "robert", "thomas", "hockey", "ranger", "daniel", "starwars",
+	"klaster", "112233", "george", "computer", "michelle", "jessica", "pepper", "zxcvbn",
+	"555555", "11111111", "131313", "freedom", "777777", "pass", "maggie", "159753",
+	"aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer", "love",
+	"ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321",
+	"dallas", "austin", "thunder", "taylor", "matrix", "welcome", "admin", "login",
+	"secret", "asdfghjkl", "qwerty123", "passw0rd", "changeme", "default", "winter", "spring",
+	"autumn", "monday", "azerty", "solo", "hello", "flower", "lovely", "whatever",
+}
+
+// commonPasswordRanks maps each common password to its rank, and
+// maxDictionaryMatch is the length of the longest one.
+var commonPasswordRanks, maxDictionaryMatch = rankCommonPasswords()
+
+// leetSubstitutions maps the characters commonly swapped into words back to
+// the letters they stand for.
+var leetSubstitutions = strings.NewReplacer(
+	"@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t",
+)
+
+const (
+	minDictionaryMatch = 4
+	minPatternMatch    = 3
+)
+
+// PasswordStrength estimates how hard a password is to guess and returns a
+// score from 0 (trivial) to 4 (very strong), in the spirit of zxcvbn. The
+// password is split into common words, repeated characters, sequences and
+// random characters, and the split needing the fewest guesses is kept.
+func PasswordStrength(password string) int {
+	runes := []rune(password)
+	lower := make([]rune, len(runes))
+	for i, char := range runes {
+		lower[i] = unicode.ToLower(char)
+	}
+
+	// best[i] is the number of guesses needed for the password from i onwards
+	best := make([]float64, len(runes)+1)
+	best[len(runes)] = 1
+	for i := len(runes) - 1; i >= 0; i-- {
+		best[i] = float64(charsetSize(runes[i])) * best[i+1]
+		for _, match := range findMatches(runes, lower, i) {
+			if guesses := match.guesses * best[i+match.length]; guesses < best[i] {
+				best[i] = guesses
+			}
+		}
+	}
+
+	switch log := math.Log10(best[0]); {
+	case log < 3:
+		return 0
+	case log < 6:
+		return 1
+	case log < 8:
+		return 2
+	case log < 10:
+		return 3
+	}
+	return 4
+}
+
+// --- Private Methods ---
+
+func rankCommonPasswords() (map[string]int, int) {
+	ranks := make(map[string]int, len(commonPasswords))
+	longest := 0
+	for i, password := range commonPasswords {
+		ranks[password] = i + 1
+		longest = max(longest, len(password))
+	}
+	return ranks, longest
+}
+
+type strengthMatch struct {
+	length  int
+	guesses float64
+}
+
+// findMatches returns the common passwords, repeats and sequences starting at i.
+func findMatches(runes, lower []rune, i int) []strengthMatch {
+	var matches []strengthMatch
+
+	for end := i + minDictionaryMatch; end <= len(lower) && end-i <= maxDictionaryMatch; end++ {
+		word := string(lower[i:end])
+		guesses := 1.0
+
+		rank, ok := commonPasswordRanks[word]
+		if !ok {
+			if rank, ok = commonPasswordRanks[leetSubstitutions.Replace(word)]; !ok {
+				continue
+			}
+			guesses *= 2
+		}
+
+		for _, char := range runes[i:end] {
+			if unicode.IsUpper(char) {
+				guesses *= 2
+				break
+			}
+		}
+		matches = append(matches, strengthMatch{length: end - i, guesses: guesses * float64(rank)})
+	}
+
+	// A character repeated over and over
+	end := i + 1
+	for end < len(lower) && lower[end] == lower[i] {
+		end++
+	}
+	if end-i >= minPatternMatch {
+		matches = append(matches, strengthMatch{length: end - i, guesses: float64(charsetSize(lower[i]) * (end - i))})
+	}
+
+	// Characters going up or down one at a time, such as "abc" or "987"
+	if i+1 < len(lower) {
+		if step := lower[i+1] - lower[i]; step == 1 || step == -1 {
+			end := i + 2
+			for end < len(lower) && lower[end]-lower[end-1] == step {
+				end++
+			}
+			if end-i >= minPatternMatch {
+				matches = append(matches, strengthMatch{length: end - i, guesses: float64(charsetSize(lower[i]) * (end - i))})
+			}
+		}
+	}
+
+	return matches
+}
+
+// charsetSize is the number of characters an attacker has to try for a
+// character of the same class.
+func charsetSize(char rune) int {
+	switch {
+	case char >= '0' && char <= '9':
+		return 10
+	case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		return 26
+	case char < unicode.MaxASCII:
+		return 33
+	}
+	return 100
+}
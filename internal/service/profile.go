@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"golang.org/x/text/language"
+)
+
+const (
+	maxMetadataKeys      = 50
+	maxMetadataKeyLength = 64
+	maxMetadataSize      = 4096
+)
+
+// UpdateProfile applies the fields present in the request to the profile of
+// the user. Every field is validated before anything is saved.
+func (s *AuthService) UpdateProfile(userID string, updateProfileRequest dto.UpdateProfileRequest) (*model.User, error) {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if updateProfileRequest.Name != nil && *updateProfileRequest.Name != user.Name {
+		if existingUser, err := s.userRepository.FindByName(*updateProfileRequest.Name); err == nil && existingUser != nil {
+			return nil, errors.New("name already taken")
+		}
+		user.Name = *updateProfileRequest.Name
+	}
+
+	if updateProfileRequest.DisplayName != nil {
+		user.DisplayName = *updateProfileRequest.DisplayName
+	}
+
+	if updateProfileRequest.AvatarURL != nil {
+		if err := validateAvatarURL(*updateProfileRequest.AvatarURL); err != nil {
+			return nil, err
+		}
+		user.AvatarURL = *updateProfileRequest.AvatarURL
+	}
+
+	if updateProfileRequest.Locale != nil {
+		locale, err := normalizeLocale(*updateProfileRequest.Locale)
+		if err != nil {
+			return nil, err
+		}
+		user.Locale = locale
+	}
+
+	if updateProfileRequest.TimeZone != nil {
+		if err := validateTimeZone(*updateProfileRequest.TimeZone); err != nil {
+			return nil, err
+		}
+		user.TimeZone = *updateProfileRequest.TimeZone
+	}
+
+	if updateProfileRequest.Metadata != nil {
+		if err := validateMetadata(updateProfileRequest.Metadata); err != nil {
+			return nil, err
+		}
+		user.Metadata = updateProfileRequest.Metadata
+	}
+
+	if err := s.userRepository.UpdateProfile(user); err != nil {
+		return nil, err
+	}
+
+	return s.userRepository.FindByID(user.ID)
+}
+
+// --- Private Methods ---
+
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("invalid avatar url")
+	}
+	return nil
+}
+
+// normalizeLocale checks that the locale is a BCP 47 language tag and returns
+// its canonical form, e.g. "en-us" becomes "en-US".
+func normalizeLocale(locale string) (string, error) {
+	if locale == "" {
+		return "", nil
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", errors.New("invalid locale")
+	}
+	return tag.String(), nil
+}
+
+// validateTimeZone accepts IANA time zone names such as "Europe/Paris".
+func validateTimeZone(timeZone string) error {
+	if timeZone == "" {
+		return nil
+	}
+
+	if timeZone == "Local" {
+		return errors.New("invalid time zone")
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return errors.New("invalid time zone")
+	}
+	return nil
+}
+
+func validateMetadata(metadata map[string]interface{}) error {
+	if len(metadata) > maxMetadataKeys {
+		return errors.New("invalid metadata: too many keys")
+	}
+
+	for key := range metadata {
+		if key == "" || len(key) > maxMetadataKeyLength {
+			return errors.New("invalid metadata: keys must be 1 to 64 characters long")
+		}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil || len(data) > maxMetadataSize {
+		return errors.New("invalid metadata: must not exceed 4096 bytes")
+	}
+	return nil
+}
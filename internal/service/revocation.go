@@ -0,0 +1,68 @@
+package service
+
+import (
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+)
+
+// tokenRevoker ends sessions and rejects the tokens issued to them. The auth
+// and user services share it so that signing a user out works the same way
+// whichever flow asks for it.
+type tokenRevoker struct {
+	userRepository   repository.UserRepository
+	blacklistRepo    repository.BlacklistRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	sessionRepo      repository.SessionRepository
+}
+
+func newTokenRevoker(userRepo repository.UserRepository, blacklistRepo repository.BlacklistRepository, refreshTokenRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository) *tokenRevoker {
+	return &tokenRevoker{
+		userRepository:   userRepo,
+		blacklistRepo:    blacklistRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+	}
+}
+
+// revokeSession ends a session: its refresh token family can no longer be
+// rotated and its latest access token is blacklisted by jti.
+func (r *tokenRevoker) revokeSession(session *model.Session) error {
+	if err := r.refreshTokenRepo.RevokeFamily(session.ID); err != nil {
+		return err
+	}
+
+	if session.AccessTokenJTI != "" && time.Now().Before(session.AccessTokenExpiry) {
+		if err := r.blacklistRepo.Add(session.AccessTokenJTI, session.AccessTokenExpiry); err != nil {
+			return err
+		}
+	}
+
+	return r.sessionRepo.Revoke(session.ID)
+}
+
+// revokeAllTokens rejects every token issued to the user so far and ends all
+// of their sessions but the one to keep, if any.
+func (r *tokenRevoker) revokeAllTokens(user *model.User, keepSessionID string) error {
+	validAfter := time.Now().Truncate(time.Microsecond)
+	if err := r.userRepository.SetTokensValidAfter(user.ID, validAfter); err != nil {
+		return err
+	}
+	user.TokensValidAfter = &validAfter
+
+	sessions, err := r.sessionRepo.FindActiveByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		if sessions[i].ID == keepSessionID {
+			continue
+		}
+		if err := r.revokeSession(&sessions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
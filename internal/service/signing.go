@@ -0,0 +1,117 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/golang-jwt/jwt"
+)
+
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// loadSigningKey builds the key used to sign and verify tokens. HS256 uses the
+// shared secret, every other algorithm reads a PEM encoded private key.
+func loadSigningKey(algorithm, secret, privateKeyPath string) (*signingKey, error) {
+	if algorithm == "" || algorithm == jwt.SigningMethodHS256.Alg() {
+		if secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		return &signingKey{
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}, nil
+	}
+
+	if privateKeyPath == "" {
+		return nil, fmt.Errorf("a private key file is required for %s", algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{
+			method:    jwt.SigningMethodRS256,
+			signKey:   privateKey,
+			verifyKey: &privateKey.PublicKey,
+		}, nil
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		if privateKey.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 requires a P-256 private key")
+		}
+		return &signingKey{
+			method:    jwt.SigningMethodES256,
+			signKey:   privateKey,
+			verifyKey: &privateKey.PublicKey,
+		}, nil
+	case jwt.SigningMethodEdDSA.Alg():
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{
+			method:    jwt.SigningMethodEdDSA,
+			signKey:   privateKey,
+			verifyKey: privateKey.(crypto.Signer).Public(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+}
+
+// jwk returns the public JSON Web Key of an asymmetric key. Symmetric keys are
+// never published.
+func (k *signingKey) jwk() (dto.JWK, bool) {
+	jwk := dto.JWK{
+		Kid: k.id,
+		Use: "sig",
+		Alg: k.method.Alg(),
+	}
+
+	switch publicKey := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return dto.JWK{}, false
+	}
+
+	return jwk, true
+}
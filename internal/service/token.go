@@ -0,0 +1,243 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/golang-jwt/jwt"
+	"github.com/spf13/viper"
+)
+
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+type TokenService struct {
+	keyRingPath        string
+	keyRingReload      time.Duration
+	issuer             string
+	audience           string
+	accessTokenExpiry  time.Duration
+	refreshTokenExpiry time.Duration
+
+	mu               sync.Mutex
+	keyRing          *keyRing
+	keyRingLoadedAt  time.Time
+	keyRingReloading bool
+}
+
+func NewTokenService() (*TokenService, error) {
+	keyRing, err := loadConfiguredKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenService{
+		keyRingPath:        viper.GetString("jwt.keyring_path"),
+		keyRingReload:      viper.GetDuration("jwt.keyring_reload_interval"),
+		keyRing:            keyRing,
+		keyRingLoadedAt:    time.Now(),
+		issuer:             viper.GetString("jwt.issuer"),
+		audience:           viper.GetString("jwt.audience"),
+		accessTokenExpiry:  viper.GetDuration("jwt.access_token_expiry"),
+		refreshTokenExpiry: viper.GetDuration("jwt.refresh_token_expiry"),
+	}, nil
+}
+
+// GenerateToken signs a token of the given type for the subject and session
+// and returns it with its claims.
+func (s *TokenService) GenerateToken(subject, sessionID, tokenType string) (string, jwt.MapClaims, error) {
+	var expiry time.Duration
+	switch tokenType {
+	case AccessTokenType:
+		expiry = s.accessTokenExpiry
+	case RefreshTokenType:
+		expiry = s.refreshTokenExpiry
+	default:
+		return "", nil, errors.New("unknown token type")
+	}
+
+	jti, err := generateTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// iat keeps microsecond precision so that a token issued right after a
+	// user's tokens_valid_after cut-off is not rejected along with older ones.
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":        subject,
+		"token_type": tokenType,
+		"iss":        s.issuer,
+		"aud":        s.audience,
+		"jti":        jti,
+		"iat":        float64(now.UnixMicro()) / 1e6,
+		"nbf":        now.Unix(),
+		"exp":        now.Add(expiry).Unix(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	signingKey := s.currentKeyRing().active()
+	token := jwt.NewWithClaims(signingKey.method, claims)
+	token.Header["kid"] = signingKey.id
+	tokenString, err := token.SignedString(signingKey.signKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// ParseToken verifies the signature and registered claims of a token and
+// rejects it unless it carries the expected token type.
+func (s *TokenService) ParseToken(tokenString, tokenType string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+		signingKey, ok := s.currentKeyRing().lookup(keyID)
+		if !ok || token.Method.Alg() != signingKey.method.Alg() {
+			return nil, jwt.ErrInvalidKey
+		}
+		return signingKey.verifyKey, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	now := time.Now().Unix()
+	if !claims.VerifyExpiresAt(now, true) || !claims.VerifyIssuedAt(now, true) || !claims.VerifyNotBefore(now, true) {
+		return nil, errors.New("invalid token")
+	}
+
+	if !claims.VerifyIssuer(s.issuer, true) || !claims.VerifyAudience(s.audience, true) {
+		return nil, errors.New("invalid token issuer or audience")
+	}
+
+	if claimType, _ := claims["token_type"].(string); claimType != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	if jti, _ := claims["jti"].(string); jti == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// JWKS returns the public keys that can be used to verify issued tokens.
+func (s *TokenService) JWKS() dto.JWKSResponse {
+	response := dto.JWKSResponse{Keys: []dto.JWK{}}
+	for _, signingKey := range s.currentKeyRing().keys {
+		if jwk, ok := signingKey.jwk(); ok {
+			response.Keys = append(response.Keys, jwk)
+		}
+	}
+	return response
+}
+
+// ExpiryFromClaims returns the expiry time carried by the exp claim.
+func ExpiryFromClaims(claims jwt.MapClaims) time.Time {
+	switch exp := claims["exp"].(type) {
+	case float64:
+		return time.Unix(int64(exp), 0)
+	case int64:
+		return time.Unix(exp, 0)
+	}
+	return time.Time{}
+}
+
+// IssuedAtFromClaims returns the issue time carried by the iat claim.
+func IssuedAtFromClaims(claims jwt.MapClaims) time.Time {
+	switch iat := claims["iat"].(type) {
+	case float64:
+		return time.UnixMicro(int64(math.Round(iat * 1e6)))
+	case int64:
+		return time.Unix(iat, 0)
+	}
+	return time.Time{}
+}
+
+// IssuedBeforeCutoff reports whether a token was issued before the user's
+// tokens_valid_after cut-off and must therefore be rejected.
+func IssuedBeforeCutoff(user *model.User, claims jwt.MapClaims) bool {
+	return user.TokensValidAfter != nil && IssuedAtFromClaims(claims).Before(*user.TokensValidAfter)
+}
+
+// HasLegacySubject reports whether a token was issued before the subject
+// became the user's UUID and still carries the email. Those tokens cannot be
+// matched to a user any more, the user has to log in again.
+func HasLegacySubject(claims jwt.MapClaims) bool {
+	subject, _ := claims["sub"].(string)
+	return strings.Contains(subject, "@")
+}
+
+// currentKeyRing returns the loaded key ring. Every reload interval the key
+// ring directory is read again in the background, so that keys added and
+// promoted by cmd/rotate-keys apply without a restart.
+func (s *TokenService) currentKeyRing() *keyRing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keyRingPath != "" && s.keyRingReload > 0 && !s.keyRingReloading && time.Since(s.keyRingLoadedAt) > s.keyRingReload {
+		s.keyRingReloading = true
+		go s.reloadKeyRing()
+	}
+	return s.keyRing
+}
+
+func (s *TokenService) reloadKeyRing() {
+	keyRing, err := loadKeyRing(s.keyRingPath)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keyRingReloading = false
+	s.keyRingLoadedAt = time.Now()
+	if err != nil {
+		log.Printf("Failed to reload the signing key ring: %v", err)
+		return
+	}
+	s.keyRing = keyRing
+}
+
+// loadConfiguredKeyRing loads the key ring directory when one is configured and
+// falls back to the single key set in config.yaml otherwise.
+func loadConfiguredKeyRing() (*keyRing, error) {
+	if keyRingPath := viper.GetString("jwt.keyring_path"); keyRingPath != "" {
+		return loadKeyRing(keyRingPath)
+	}
+
+	signingKey, err := loadSigningKey(
+		viper.GetString("jwt.algorithm"),
+		viper.GetString("jwt.secret"),
+		viper.GetString("jwt.private_key_path"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return newSingleKeyRing(signingKey), nil
+}
+
+func generateTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -8,15 +8,19 @@ import (
 type UserService interface {
 	GetAllUsers() ([]model.User, error)
 	RemoveAllUsers() error
+	RevokeTokens(userID string) error
+	UnlockAccount(userID string) error
 }
 
 type userService struct {
 	userRepository repository.UserRepository
+	revoker        *tokenRevoker
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, blacklistRepo repository.BlacklistRepository, refreshTokenRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository) UserService {
 	return &userService{
 		userRepository: userRepo,
+		revoker:        newTokenRevoker(userRepo, blacklistRepo, refreshTokenRepo, sessionRepo),
 	}
 }
 
@@ -27,3 +31,22 @@ func (s *userService) GetAllUsers() ([]model.User, error) {
 func (s *userService) RemoveAllUsers() error {
 	return s.userRepository.RemoveAll()
 }
+
+// RevokeTokens forces the user to sign in again by rejecting every token
+// issued to them until now and ending all of their sessions.
+func (s *userService) RevokeTokens(userID string) error {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return err
+	}
+	return s.revoker.revokeAllTokens(user, "")
+}
+
+// UnlockAccount lifts the lockout of the user after too many failed logins.
+func (s *userService) UnlockAccount(userID string) error {
+	user, err := s.userRepository.FindByUUID(userID)
+	if err != nil {
+		return err
+	}
+	return s.userRepository.ClearFailedLogins(user.ID)
+}
@@ -2,12 +2,16 @@ package integration
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/YoubaImkf/go-auth-api/internal/controller"
 	"github.com/YoubaImkf/go-auth-api/internal/dto"
@@ -19,8 +23,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type MockEmailService struct {
@@ -32,6 +38,36 @@ func (m *MockEmailService) SendPasswordResetEmail(to, token string) error {
 	return args.Error(0)
 }
 
+func (m *MockEmailService) SendVerificationEmail(to, token string) error {
+	args := m.Called(to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendPasswordChangedEmail(to string) error {
+	args := m.Called(to)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendEmailChangeConfirmation(to, token string) error {
+	args := m.Called(to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendEmailChangedNotice(to, newEmail, undoToken string) error {
+	args := m.Called(to, newEmail, undoToken)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendAccountDeletionEmail(to string, deletionDate time.Time) error {
+	args := m.Called(to, deletionDate)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendAccountLockedEmail(to, token string, lockedUntil time.Time) error {
+	args := m.Called(to, token, lockedUntil)
+	return args.Error(0)
+}
+
 type AuthIntegrationTestSuite struct {
 	suite.Suite
 	db           *gorm.DB
@@ -55,7 +91,7 @@ func (suite *AuthIntegrationTestSuite) SetupSuite() {
 
 	suite.emailService = new(MockEmailService)
 
-	suite.db.AutoMigrate(&model.User{}, &model.PasswordReset{}, &model.BlacklistedToken{})
+	suite.db.AutoMigrate(&model.User{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.EmailChange{}, &model.PasswordHistory{}, &model.AccountUnlock{}, &model.RevokedToken{}, &model.RefreshToken{}, &model.Session{})
 
 	suite.router = suite.setupTestRouter()
 }
@@ -67,21 +103,54 @@ func (suite *AuthIntegrationTestSuite) setupTestRouter() *gin.Engine {
 
 	userRepo := repository.NewPostgresUserRepository(suite.db)
 	blacklistRepo := repository.NewPostgresBlacklistRepository(suite.db)
+	refreshTokenRepo := repository.NewPostgresRefreshTokenRepository(suite.db)
+	sessionRepo := repository.NewPostgresSessionRepository(suite.db)
+
+	tokenService, err := service.NewTokenService()
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	passwordHasher, err := service.NewPasswordHasher()
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	authService := service.NewAuthService(userRepo, blacklistRepo, refreshTokenRepo, sessionRepo, suite.emailService, tokenService, service.NewPasswordPolicy(nil), passwordHasher)
 
-	authService := service.NewAuthService(userRepo, blacklistRepo, suite.emailService)
+	userService := service.NewUserService(userRepo, blacklistRepo, refreshTokenRepo, sessionRepo)
 
 	authController := controller.NewAuthController(authService)
+	userController := controller.NewUserController(userService)
 
 	router.POST("/register", authController.Register)
 	router.POST("/login", authController.Login)
+	router.POST("/refresh", authController.RefreshToken)
 	router.POST("/forgot-password", authController.ForgotPassword)
 	router.POST("/reset-password", authController.ResetPassword)
+	router.POST("/verify-email", authController.VerifyEmail)
+	router.POST("/resend-verification", authController.ResendVerification)
+	router.POST("/undo-email-change", authController.UndoEmailChange)
+	router.POST("/unlock-account", authController.UnlockAccount)
+
+	admin := router.Group("/")
+	admin.Use(middleware.AdminMiddleware())
+	{
+		admin.POST("/users/:id/revoke-tokens", userController.RevokeTokens)
+		admin.POST("/users/:id/unlock", userController.UnlockAccount)
+	}
 
 	protected := router.Group("/")
-	protected.Use(middleware.AuthMiddleware(blacklistRepo))
+	protected.Use(middleware.AuthMiddleware(tokenService, blacklistRepo, userRepo, sessionRepo))
 	{
 		protected.POST("/logout", authController.Logout)
 		protected.GET("/me", authController.GetProfile)
+		protected.PATCH("/me", authController.UpdateProfile)
+		protected.DELETE("/me", authController.DeleteAccount)
+		protected.POST("/me/password", authController.ChangePassword)
+		protected.POST("/me/email", authController.RequestEmailChange)
+		protected.POST("/me/email/confirm", authController.ConfirmEmailChange)
+		protected.GET("/sessions", authController.ListSessions)
+		protected.DELETE("/sessions/:id", authController.RevokeSession)
+		protected.DELETE("/sessions", authController.RevokeAllSessions)
 	}
 
 	return router
@@ -89,13 +158,19 @@ func (suite *AuthIntegrationTestSuite) setupTestRouter() *gin.Engine {
 
 func (suite *AuthIntegrationTestSuite) SetupTest() {
 	// Clean up database before each test
-	suite.db.Exec("TRUNCATE users, password_resets, blacklisted_tokens RESTART IDENTITY CASCADE")
+	suite.db.Exec("TRUNCATE users, password_resets, email_verifications, email_changes, password_histories, account_unlocks, revoked_tokens, refresh_tokens, sessions RESTART IDENTITY CASCADE")
 
 	// Reset mock expectations
 	suite.emailService.ExpectedCalls = nil
 
 	// Setup default mock behavior for email service
 	suite.emailService.On("SendPasswordResetEmail", mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendVerificationEmail", mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendPasswordChangedEmail", mock.Anything).Return(nil)
+	suite.emailService.On("SendEmailChangeConfirmation", mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendEmailChangedNotice", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendAccountDeletionEmail", mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendAccountLockedEmail", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 }
 
 func (suite *AuthIntegrationTestSuite) TestFullAuthFlow() {
@@ -141,11 +216,15 @@ func (suite *AuthIntegrationTestSuite) TestFullAuthFlow() {
 	forgotResp := suite.performRequest("POST", "/forgot-password", forgotPayload)
 	suite.Equal(http.StatusOK, forgotResp.Code)
 
-	var forgotResponse map[string]interface{}
-	suite.NoError(json.Unmarshal(forgotResp.Body.Bytes(), &forgotResponse))
-	resetToken := forgotResponse["token"].(string)
+	// The token only travels by email, and unknown addresses get the same answer
+	suite.NotContains(forgotResp.Body.String(), "token")
+	resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
 	suite.NotEmpty(resetToken)
 
+	unknownForgotResp := suite.performRequest("POST", "/forgot-password", dto.ForgotPasswordRequest{Email: "unknown@example.com"})
+	suite.Equal(forgotResp.Code, unknownForgotResp.Code)
+	suite.Equal(forgotResp.Body.String(), unknownForgotResp.Body.String())
+
 	// 5. Reset password
 	resetPayload := dto.ResetPasswordRequest{
 		Token:       resetToken,
@@ -158,6 +237,8 @@ func (suite *AuthIntegrationTestSuite) TestFullAuthFlow() {
 	loginPayload.Password = "NewPassword123!"
 	loginResp = suite.performRequest("POST", "/login", loginPayload)
 	suite.Equal(http.StatusOK, loginResp.Code)
+	// Tokens issued before the reset are no longer valid
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &loginResponse))
 
 	// 7. Logout
 	logoutResp := suite.performAuthorizedRequest("POST", "/logout", nil, loginResponse.AccessToken)
@@ -187,6 +268,267 @@ func (suite *AuthIntegrationTestSuite) TestInvalidLoginAttempt() {
 	suite.Equal(http.StatusUnauthorized, loginResp.Code)
 }
 
+func (suite *AuthIntegrationTestSuite) TestRefreshToken() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "elon Musk",
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	refreshPayload := dto.RefreshTokenRequest{
+		RefreshToken: registerResponse.RefreshToken,
+	}
+	refreshResp := suite.performRequest("POST", "/refresh", refreshPayload)
+	suite.Equal(http.StatusOK, refreshResp.Code)
+
+	var refreshResponse dto.RefreshTokenResponse
+	suite.NoError(json.Unmarshal(refreshResp.Body.Bytes(), &refreshResponse))
+	suite.NotEmpty(refreshResponse.AccessToken)
+	suite.NotEmpty(refreshResponse.RefreshToken)
+	suite.Equal("elon@example.com", refreshResponse.User.Email)
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, refreshResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestRefreshTokenInvalid() {
+	refreshPayload := dto.RefreshTokenRequest{
+		RefreshToken: "invalid_token",
+	}
+	refreshResp := suite.performRequest("POST", "/refresh", refreshPayload)
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestRefreshTokenReuseRevokesFamily() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "elon Musk",
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	firstPayload := dto.RefreshTokenRequest{RefreshToken: registerResponse.RefreshToken}
+	firstResp := suite.performRequest("POST", "/refresh", firstPayload)
+	suite.Equal(http.StatusOK, firstResp.Code)
+
+	var firstResponse dto.RefreshTokenResponse
+	suite.NoError(json.Unmarshal(firstResp.Body.Bytes(), &firstResponse))
+
+	// Replaying the rotated-out token is detected as reuse
+	replayResp := suite.performRequest("POST", "/refresh", firstPayload)
+	suite.Equal(http.StatusUnauthorized, replayResp.Code)
+
+	// and the whole family, including the latest token, is revoked
+	latestPayload := dto.RefreshTokenRequest{RefreshToken: firstResponse.RefreshToken}
+	latestResp := suite.performRequest("POST", "/refresh", latestPayload)
+	suite.Equal(http.StatusUnauthorized, latestResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLogoutRevokesRefreshToken() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "elon Musk",
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	logoutPayload := dto.LogoutRequest{
+		RefreshToken: registerResponse.RefreshToken,
+	}
+	logoutResp := suite.performAuthorizedRequest("POST", "/logout", logoutPayload, registerResponse.AccessToken)
+	suite.Equal(http.StatusNoContent, logoutResp.Code)
+
+	refreshPayload := dto.RefreshTokenRequest{
+		RefreshToken: registerResponse.RefreshToken,
+	}
+	refreshResp := suite.performRequest("POST", "/refresh", refreshPayload)
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestSessions() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "elon Musk",
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	loginPayload := dto.LoginRequest{
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	var loginResponse dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &loginResponse))
+
+	// 1. Both devices are listed, the login one being the current session
+	sessionsResp := suite.performAuthorizedRequest("GET", "/sessions", nil, loginResponse.AccessToken)
+	suite.Equal(http.StatusOK, sessionsResp.Code)
+
+	var sessions []dto.SessionResponse
+	suite.NoError(json.Unmarshal(sessionsResp.Body.Bytes(), &sessions))
+	suite.Len(sessions, 2)
+
+	var otherSessionID string
+	for _, session := range sessions {
+		if !session.Current {
+			otherSessionID = session.ID
+		}
+	}
+	suite.NotEmpty(otherSessionID)
+
+	// 2. Revoking the other session signs out its tokens, including the ones
+	// issued before its last refresh
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: registerResponse.RefreshToken})
+	suite.Equal(http.StatusOK, refreshResp.Code)
+
+	var otherRefreshResponse dto.RefreshTokenResponse
+	suite.NoError(json.Unmarshal(refreshResp.Body.Bytes(), &otherRefreshResponse))
+
+	revokeResp := suite.performAuthorizedRequest("DELETE", "/sessions/"+otherSessionID, nil, loginResponse.AccessToken)
+	suite.Equal(http.StatusNoContent, revokeResp.Code)
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, otherRefreshResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+
+	refreshResp = suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: otherRefreshResponse.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	// 3. Logging out everywhere signs out the current session too
+	refreshResp = suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: loginResponse.RefreshToken})
+	suite.Equal(http.StatusOK, refreshResp.Code)
+
+	var currentRefreshResponse dto.RefreshTokenResponse
+	suite.NoError(json.Unmarshal(refreshResp.Body.Bytes(), &currentRefreshResponse))
+
+	revokeAllResp := suite.performAuthorizedRequest("DELETE", "/sessions", nil, currentRefreshResponse.AccessToken)
+	suite.Equal(http.StatusNoContent, revokeAllResp.Code)
+
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, loginResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, currentRefreshResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestTokenTypeCrossUse() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "elon Musk",
+		Email:    "elon@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	// A refresh token must not be accepted as an access token
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.RefreshToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+
+	// and an access token must not be accepted as a refresh token
+	refreshPayload := dto.RefreshTokenRequest{
+		RefreshToken: registerResponse.AccessToken,
+	}
+	refreshResp := suite.performRequest("POST", "/refresh", refreshPayload)
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestJanitorPurgesExpiredRows() {
+	userRepo := repository.NewPostgresUserRepository(suite.db)
+	blacklistRepo := repository.NewPostgresBlacklistRepository(suite.db)
+	refreshTokenRepo := repository.NewPostgresRefreshTokenRepository(suite.db)
+	sessionRepo := repository.NewPostgresSessionRepository(suite.db)
+
+	expired := time.Now().Add(-time.Hour)
+	valid := time.Now().Add(time.Hour)
+	for i := range 3 {
+		suite.NoError(blacklistRepo.Add(fmt.Sprintf("expired-%d", i), expired))
+		suite.NoError(userRepo.StorePasswordResetToken(fmt.Sprintf("expired-%d@example.com", i), fmt.Sprintf("expired-%d", i), expired))
+		suite.NoError(userRepo.StoreEmailVerificationToken(fmt.Sprintf("expired-%d@example.com", i), fmt.Sprintf("expired-%d", i), expired))
+		suite.NoError(userRepo.StoreUnlockToken(uint(100+i), fmt.Sprintf("expired-%d", i), expired))
+		suite.NoError(refreshTokenRepo.Create(&model.RefreshToken{JTI: fmt.Sprintf("expired-%d", i), FamilyID: "family", Subject: "expired@example.com", Expiry: expired}))
+	}
+	suite.NoError(blacklistRepo.Add("valid", valid))
+	suite.NoError(userRepo.StorePasswordResetToken("valid@example.com", "valid", valid))
+	suite.NoError(userRepo.StoreEmailVerificationToken("valid@example.com", "valid", valid))
+	suite.NoError(userRepo.StoreUnlockToken(200, "valid", valid))
+	suite.NoError(refreshTokenRepo.Create(&model.RefreshToken{JTI: "valid", FamilyID: "family", Subject: "valid@example.com", Expiry: valid}))
+
+	// A pending change whose link expired and a confirmed one whose undo
+	// window is over are removed, the others can still be used
+	suite.NoError(userRepo.StoreEmailChange(&model.EmailChange{Token: "expired-pending", UndoToken: "expired-pending-undo", UserID: 100, Expiry: expired}))
+	suite.NoError(userRepo.StoreEmailChange(&model.EmailChange{Token: "expired-confirmed", UndoToken: "expired-confirmed-undo", UserID: 101, Expiry: expired, ConfirmedAt: &expired, UndoExpiry: &expired}))
+	suite.NoError(userRepo.StoreEmailChange(&model.EmailChange{Token: "valid-pending", UndoToken: "valid-pending-undo", UserID: 102, Expiry: valid}))
+	suite.NoError(userRepo.StoreEmailChange(&model.EmailChange{Token: "valid-confirmed", UndoToken: "valid-confirmed-undo", UserID: 103, Expiry: expired, ConfirmedAt: &expired, UndoExpiry: &valid}))
+
+	// Sessions revoked or unused for longer than the retention are removed
+	longAgo := time.Now().Add(-72 * time.Hour)
+	recently := time.Now().Add(-time.Hour)
+	suite.NoError(sessionRepo.Create(&model.Session{ID: "revoked-long-ago", UserID: 100, LastUsedAt: recently, RevokedAt: &longAgo}))
+	suite.NoError(sessionRepo.Create(&model.Session{ID: "unused-long-ago", UserID: 100, LastUsedAt: longAgo}))
+	suite.NoError(sessionRepo.Create(&model.Session{ID: "revoked-recently", UserID: 100, LastUsedAt: longAgo, RevokedAt: &recently}))
+	suite.NoError(sessionRepo.Create(&model.Session{ID: "active", UserID: 100, LastUsedAt: recently}))
+
+	janitor := service.NewJanitor(blacklistRepo, userRepo, refreshTokenRepo, sessionRepo)
+	result, err := janitor.Purge()
+
+	suite.NoError(err)
+	suite.Equal(int64(3), result.BlacklistedTokens)
+	suite.Equal(int64(3), result.PasswordResets)
+	suite.Equal(int64(3), result.EmailVerifications)
+	suite.Equal(int64(2), result.EmailChanges)
+	suite.Equal(int64(3), result.AccountUnlocks)
+	suite.Equal(int64(3), result.RefreshTokens)
+	suite.Equal(int64(2), result.Sessions)
+	suite.True(blacklistRepo.IsBlacklisted("valid"))
+
+	_, err = refreshTokenRepo.FindByJTI("valid")
+	suite.NoError(err)
+	_, err = userRepo.FindEmailChangeByToken("valid-pending")
+	suite.NoError(err)
+	_, err = userRepo.FindEmailChangeByUndoToken("valid-confirmed-undo")
+	suite.NoError(err)
+	_, err = sessionRepo.FindByID("active")
+	suite.NoError(err)
+	_, err = sessionRepo.FindByID("revoked-recently")
+	suite.NoError(err)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLegacyBlacklistMigration() {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"elon@example.com","jti":"legacy-jti"}`))
+	legacyToken := "eyJhbGciOiJIUzI1NiJ9." + payload + ".signature"
+
+	suite.db.Exec("CREATE TABLE blacklisted_tokens (token text PRIMARY KEY, expiry timestamp with time zone NOT NULL)")
+	suite.db.Exec("INSERT INTO blacklisted_tokens (token, expiry) VALUES (?, ?)", legacyToken, time.Now().Add(time.Hour))
+
+	blacklistRepo := repository.NewPostgresBlacklistRepository(suite.db)
+
+	suite.True(blacklistRepo.IsBlacklisted("legacy-jti"))
+	suite.False(suite.db.HasTable("blacklisted_tokens"))
+}
+
 func (suite *AuthIntegrationTestSuite) TestProtectedRouteWithoutAuth() {
 	resp := suite.performRequest("GET", "/me", nil)
 	suite.Equal(http.StatusUnauthorized, resp.Code)
@@ -210,11 +552,8 @@ func (suite *AuthIntegrationTestSuite) TestResetPasswordTokenReuse() {
 	}
 	forgotResp := suite.performRequest("POST", "/forgot-password", forgotPayload)
 
-	var forgotResponse map[string]any
-	// parse the JSON response from the forgot password API into the 'forgotResponse' map.
-	// 'forgotResp.Body.Bytes()' gets the response body as a byte slice.
-	suite.NoError(json.Unmarshal(forgotResp.Body.Bytes(), &forgotResponse))
-	resetToken := forgotResponse["token"].(string)
+	suite.Equal(http.StatusOK, forgotResp.Code)
+	resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
 
 	resetPayload := dto.ResetPasswordRequest{
 		Token:       resetToken,
@@ -228,34 +567,973 @@ func (suite *AuthIntegrationTestSuite) TestResetPasswordTokenReuse() {
 	suite.Equal(http.StatusUnauthorized, secondResetResp.Code)
 }
 
-// --- Pirvate Method ---
-func (suite *AuthIntegrationTestSuite) performRequest(method, path string, payload interface{}) *httptest.ResponseRecorder {
-	var body []byte
-	if payload != nil {
-		body, _ = json.Marshal(payload)
+func (suite *AuthIntegrationTestSuite) TestResetPasswordRevokesExistingTokens() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Reset User",
+		Email:    "reset@example.com",
+		Password: "Password123!",
 	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
 
-	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
 
-	w := httptest.NewRecorder()
-	suite.router.ServeHTTP(w, req)
-	return w
+	forgotResp := suite.performRequest("POST", "/forgot-password", dto.ForgotPasswordRequest{Email: "reset@example.com"})
+	suite.Equal(http.StatusOK, forgotResp.Code)
+	resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
+
+	resetPayload := dto.ResetPasswordRequest{
+		Token:       resetToken,
+		NewPassword: "NewPassword123!",
+	}
+	resetResp := suite.performRequest("POST", "/reset-password", resetPayload)
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+
+	// Tokens issued before the reset are rejected
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: registerResponse.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	// Tokens issued after the reset still work
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "reset@example.com", Password: "NewPassword123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	var loginResponse dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &loginResponse))
+
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, loginResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
 }
 
-func (suite *AuthIntegrationTestSuite) performAuthorizedRequest(method, path string, payload interface{}, token string) *httptest.ResponseRecorder {
-	req := httptest.NewRequest(method, path, nil)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-	req.Header.Set("Content-Type", "application/json")
+func (suite *AuthIntegrationTestSuite) TestAdminRevokeTokens() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Revoked User",
+		Email:    "revoked@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
 
-	if payload != nil {
-		body, _ := json.Marshal(payload)
-		req.Body = io.NopCloser(bytes.NewBuffer(body))
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	// Only callers holding the admin key may revoke
+	revokeResp := suite.performRequest("POST", "/users/"+registerResponse.User.ID+"/revoke-tokens", nil)
+	suite.Equal(http.StatusUnauthorized, revokeResp.Code)
+	revokeResp = suite.performAdminRequest("POST", "/users/"+registerResponse.User.ID+"/revoke-tokens", "wrong-admin-key")
+	suite.Equal(http.StatusUnauthorized, revokeResp.Code)
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+
+	loginPayload := dto.LoginRequest{Email: "revoked@example.com", Password: "Password123!"}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	revokeResp = suite.performAdminRequest("POST", "/users/"+registerResponse.User.ID+"/revoke-tokens", suite.config.AdminAPIKey)
+	suite.Equal(http.StatusNoContent, revokeResp.Code)
+
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: registerResponse.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	// The sessions are revoked as well, only the new one is listed
+	var newSession dto.LoginResponse
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &newSession))
+
+	sessionsResp := suite.performAuthorizedRequest("GET", "/sessions", nil, newSession.AccessToken)
+	suite.Equal(http.StatusOK, sessionsResp.Code)
+	var sessions []dto.SessionResponse
+	suite.NoError(json.Unmarshal(sessionsResp.Body.Bytes(), &sessions))
+	suite.Len(sessions, 1)
+
+	missingResp := suite.performAdminRequest("POST", "/users/00000000-0000-4000-8000-000000000000/revoke-tokens", suite.config.AdminAPIKey)
+	suite.Equal(http.StatusNotFound, missingResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestEmailVerificationRequiredForLogin() {
+	viper.Set("email_verification.required", true)
+	suite.router = suite.setupTestRouter()
+	defer func() {
+		viper.Set("email_verification.required", false)
+		suite.router = suite.setupTestRouter()
+	}()
+
+	registerPayload := dto.RegisterRequest{
+		Name:     "Unverified User",
+		Email:    "unverified@example.com",
+		Password: "Password123!",
 	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+	suite.emailService.AssertCalled(suite.T(), "SendVerificationEmail", "unverified@example.com", mock.Anything)
 
-	w := httptest.NewRecorder()
-	suite.router.ServeHTTP(w, req)
-	return w
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+	suite.Empty(registerResponse.AccessToken)
+
+	loginPayload := dto.LoginRequest{
+		Email:    "unverified@example.com",
+		Password: "Password123!",
+	}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusForbidden, loginResp.Code)
+
+	// The token only travels by email
+	var verificationToken string
+	var sent int
+	suite.emailService.ExpectedCalls = nil
+	suite.emailService.On("SendVerificationEmail", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { verificationToken = args.String(1); sent++ }).
+		Return(nil)
+
+	resendResp := suite.performRequest("POST", "/resend-verification", dto.ResendVerificationRequest{Email: "unverified@example.com"})
+	suite.Equal(http.StatusOK, resendResp.Code)
+	suite.NotContains(resendResp.Body.String(), "token")
+	suite.Require().NotEmpty(verificationToken)
+	verifyPayload := dto.VerifyEmailRequest{Token: verificationToken}
+
+	// Unknown addresses get the same answer
+	unknownResp := suite.performRequest("POST", "/resend-verification", dto.ResendVerificationRequest{Email: "unknown@example.com"})
+	suite.Equal(resendResp.Code, unknownResp.Code)
+	suite.Equal(resendResp.Body.String(), unknownResp.Body.String())
+
+	verifyResp := suite.performRequest("POST", "/verify-email", verifyPayload)
+	suite.Equal(http.StatusNoContent, verifyResp.Code)
+
+	// The token is single use
+	verifyResp = suite.performRequest("POST", "/verify-email", verifyPayload)
+	suite.Equal(http.StatusUnauthorized, verifyResp.Code)
+
+	// Verified addresses too, and no new link is sent
+	resendResp = suite.performRequest("POST", "/resend-verification", dto.ResendVerificationRequest{Email: "unverified@example.com"})
+	suite.Equal(unknownResp.Body.String(), resendResp.Body.String())
+	suite.Equal(1, sent)
+
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestChangePassword() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Change User",
+		Email:    "change@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var otherSession dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &otherSession))
+
+	loginPayload := dto.LoginRequest{
+		Email:    "change@example.com",
+		Password: "Password123!",
+	}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	var currentSession dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &currentSession))
+
+	wrongPayload := dto.ChangePasswordRequest{
+		CurrentPassword: "WrongPassword123!",
+		NewPassword:     "NewPassword123!",
+	}
+	wrongResp := suite.performAuthorizedRequest("POST", "/me/password", wrongPayload, currentSession.AccessToken)
+	suite.Equal(http.StatusUnauthorized, wrongResp.Code)
+
+	changePayload := dto.ChangePasswordRequest{
+		CurrentPassword: "Password123!",
+		NewPassword:     "NewPassword123!",
+	}
+	changeResp := suite.performAuthorizedRequest("POST", "/me/password", changePayload, currentSession.AccessToken)
+	suite.Equal(http.StatusOK, changeResp.Code)
+	suite.emailService.AssertCalled(suite.T(), "SendPasswordChangedEmail", "change@example.com")
+
+	var changeResponse dto.ChangePasswordResponse
+	suite.NoError(json.Unmarshal(changeResp.Body.Bytes(), &changeResponse))
+
+	// The other session is signed out
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: otherSession.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	// The current session continues with the new tokens only
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, currentSession.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, changeResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+
+	sessionsResp := suite.performAuthorizedRequest("GET", "/sessions", nil, changeResponse.AccessToken)
+	var sessions []dto.SessionResponse
+	suite.NoError(json.Unmarshal(sessionsResp.Body.Bytes(), &sessions))
+	suite.Len(sessions, 1)
+
+	refreshResp = suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: changeResponse.RefreshToken})
+	suite.Equal(http.StatusOK, refreshResp.Code)
+
+	loginPayload.Password = "NewPassword123!"
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestChangeEmail() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Moving User",
+		Email:    "old@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var otherSession dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &otherSession))
+
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "old@example.com", Password: "Password123!"})
+	var currentSession dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &currentSession))
+
+	// A pending password reset follows the user to the new address
+	forgotResp := suite.performRequest("POST", "/forgot-password", dto.ForgotPasswordRequest{Email: "old@example.com"})
+	suite.Equal(http.StatusOK, forgotResp.Code)
+	resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
+
+	changePayload := dto.ChangeEmailRequest{
+		NewEmail: "new@example.com",
+		Password: "Password123!",
+	}
+	changeResp := suite.performAuthorizedRequest("POST", "/me/email", changePayload, currentSession.AccessToken)
+	suite.Equal(http.StatusOK, changeResp.Code)
+	suite.emailService.AssertCalled(suite.T(), "SendEmailChangeConfirmation", "new@example.com", mock.Anything)
+
+	// The token only travels to the new address
+	suite.NotContains(changeResp.Body.String(), "token")
+	confirmPayload := dto.ConfirmEmailChangeRequest{Token: suite.lastEmailArgument("SendEmailChangeConfirmation", 1)}
+
+	// Nothing changes until the new address is confirmed
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, currentSession.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+
+	confirmResp := suite.performAuthorizedRequest("POST", "/me/email/confirm", confirmPayload, currentSession.AccessToken)
+	suite.Equal(http.StatusOK, confirmResp.Code)
+	suite.emailService.AssertCalled(suite.T(), "SendEmailChangedNotice", "old@example.com", "new@example.com", mock.Anything)
+
+	var confirmResponse dto.ConfirmEmailChangeResponse
+	suite.NoError(json.Unmarshal(confirmResp.Body.Bytes(), &confirmResponse))
+	suite.Equal("new@example.com", confirmResponse.User.Email)
+
+	// Tokens issued for the old address are no longer accepted
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, currentSession.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: otherSession.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, confirmResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+
+	loginResp = suite.performRequest("POST", "/login", dto.LoginRequest{Email: "new@example.com", Password: "Password123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	resetPayload := dto.ResetPasswordRequest{
+		Token:       resetToken,
+		NewPassword: "ChangedPassword123!",
+	}
+	resetResp := suite.performRequest("POST", "/reset-password", resetPayload)
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+
+	// The confirmation link is single use
+	confirmResp = suite.performAuthorizedRequest("POST", "/me/email/confirm", confirmPayload, confirmResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, confirmResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestUndoEmailChange() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Hijacked User",
+		Email:    "victim@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	var undoToken string
+	suite.emailService.ExpectedCalls = nil
+	suite.emailService.On("SendEmailChangeConfirmation", mock.Anything, mock.Anything).Return(nil)
+	suite.emailService.On("SendEmailChangedNotice", "victim@example.com", "attacker@example.com", mock.Anything).
+		Run(func(args mock.Arguments) { undoToken = args.String(2) }).
+		Return(nil)
+
+	changePayload := dto.ChangeEmailRequest{
+		NewEmail: "attacker@example.com",
+		Password: "Password123!",
+	}
+	changeResp := suite.performAuthorizedRequest("POST", "/me/email", changePayload, registerResponse.AccessToken)
+	suite.Equal(http.StatusOK, changeResp.Code)
+
+	confirmPayload := dto.ConfirmEmailChangeRequest{Token: suite.lastEmailArgument("SendEmailChangeConfirmation", 1)}
+	confirmResp := suite.performAuthorizedRequest("POST", "/me/email/confirm", confirmPayload, registerResponse.AccessToken)
+	suite.Equal(http.StatusOK, confirmResp.Code)
+	suite.NotEmpty(undoToken)
+
+	var confirmResponse dto.ConfirmEmailChangeResponse
+	suite.NoError(json.Unmarshal(confirmResp.Body.Bytes(), &confirmResponse))
+
+	undoResp := suite.performRequest("POST", "/undo-email-change", dto.UndoEmailChangeRequest{Token: undoToken})
+	suite.Equal(http.StatusNoContent, undoResp.Code)
+
+	// Every session is signed out and the old address is back
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, confirmResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "victim@example.com", Password: "Password123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	undoResp = suite.performRequest("POST", "/undo-email-change", dto.UndoEmailChangeRequest{Token: undoToken})
+	suite.Equal(http.StatusUnauthorized, undoResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestTokenSubjectIsUserID() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Subject User",
+		Email:    "subject@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+	suite.Regexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, registerResponse.User.ID)
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(registerResponse.AccessToken, ".")[1])
+	suite.NoError(err)
+
+	var claims map[string]any
+	suite.NoError(json.Unmarshal(payload, &claims))
+	suite.Equal(registerResponse.User.ID, claims["sub"])
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	var userResponse dto.UserResponse
+	suite.NoError(json.Unmarshal(profileResp.Body.Bytes(), &userResponse))
+	suite.Equal(registerResponse.User.ID, userResponse.ID)
+	suite.Equal("subject@example.com", userResponse.Email)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLegacySubjectTokensAreRefused() {
+	suite.performRequest("POST", "/register", dto.RegisterRequest{
+		Name:     "Legacy User",
+		Email:    "legacy@example.com",
+		Password: "Password123!",
+	})
+
+	// Tokens issued by earlier versions carried the email as subject
+	tokenService, err := service.NewTokenService()
+	suite.Require().NoError(err)
+	accessToken, _, err := tokenService.GenerateToken("legacy@example.com", "", service.AccessTokenType)
+	suite.Require().NoError(err)
+	refreshToken, _, err := tokenService.GenerateToken("legacy@example.com", "", service.RefreshTokenType)
+	suite.Require().NoError(err)
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, accessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	suite.Contains(profileResp.Body.String(), "no longer supported")
+
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: refreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+	suite.Contains(refreshResp.Body.String(), "no longer supported")
+}
+
+func (suite *AuthIntegrationTestSuite) TestUpdateProfile() {
+	suite.performRequest("POST", "/register", dto.RegisterRequest{
+		Name:     "taken",
+		Email:    "taken@example.com",
+		Password: "Password123!",
+	})
+
+	registerPayload := dto.RegisterRequest{
+		Name:     "profile",
+		Email:    "profile@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+	token := registerResponse.AccessToken
+
+	updatePayload := map[string]any{
+		"name":         "profile-renamed",
+		"display_name": "Profile User",
+		"avatar_url":   "https://example.com/avatar.png",
+		"locale":       "fr-fr",
+		"time_zone":    "Europe/Paris",
+		"metadata":     map[string]any{"theme": "dark", "beta": true},
+	}
+	updateResp := suite.performAuthorizedRequest("PATCH", "/me", updatePayload, token)
+	suite.Equal(http.StatusOK, updateResp.Code)
+
+	var userResponse dto.UserResponse
+	suite.NoError(json.Unmarshal(updateResp.Body.Bytes(), &userResponse))
+	suite.Equal("profile-renamed", userResponse.Name)
+	suite.Equal("Profile User", userResponse.DisplayName)
+	suite.Equal("fr-FR", userResponse.Locale)
+	suite.Equal("Europe/Paris", userResponse.TimeZone)
+	suite.Equal("dark", userResponse.Metadata["theme"])
+	suite.False(userResponse.CreatedAt.IsZero())
+	suite.False(userResponse.UpdatedAt.Before(userResponse.CreatedAt))
+
+	// Fields that are not sent stay unchanged
+	updateResp = suite.performAuthorizedRequest("PATCH", "/me", map[string]any{"display_name": "Renamed"}, token)
+	suite.Equal(http.StatusOK, updateResp.Code)
+	suite.NoError(json.Unmarshal(updateResp.Body.Bytes(), &userResponse))
+	suite.Equal("Renamed", userResponse.DisplayName)
+	suite.Equal("https://example.com/avatar.png", userResponse.AvatarURL)
+
+	conflictResp := suite.performAuthorizedRequest("PATCH", "/me", map[string]any{"name": "taken"}, token)
+	suite.Equal(http.StatusConflict, conflictResp.Code)
+
+	invalidPayloads := []map[string]any{
+		{"name": ""},
+		{"avatar_url": "javascript:alert(1)"},
+		{"locale": "not a locale"},
+		{"time_zone": "Mars/Olympus_Mons"},
+		{"metadata": map[string]any{"": "empty key"}},
+	}
+	for _, payload := range invalidPayloads {
+		invalidResp := suite.performAuthorizedRequest("PATCH", "/me", payload, token)
+		suite.Equal(http.StatusBadRequest, invalidResp.Code, payload)
+	}
+
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, token)
+	suite.NoError(json.Unmarshal(profileResp.Body.Bytes(), &userResponse))
+	suite.Equal("profile-renamed", userResponse.Name)
+	suite.Equal("fr-FR", userResponse.Locale)
+}
+
+func (suite *AuthIntegrationTestSuite) TestDeleteAccountAndRestoreByLogin() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Leaving User",
+		Email:    "leaving@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	wrongResp := suite.performAuthorizedRequest("DELETE", "/me", dto.DeleteAccountRequest{Password: "WrongPassword123!"}, registerResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, wrongResp.Code)
+
+	deleteResp := suite.performAuthorizedRequest("DELETE", "/me", dto.DeleteAccountRequest{Password: "Password123!"}, registerResponse.AccessToken)
+	suite.Equal(http.StatusOK, deleteResp.Code)
+	suite.emailService.AssertCalled(suite.T(), "SendAccountDeletionEmail", "leaving@example.com", mock.Anything)
+
+	// Every session is signed out
+	profileResp := suite.performAuthorizedRequest("GET", "/me", nil, registerResponse.AccessToken)
+	suite.Equal(http.StatusUnauthorized, profileResp.Code)
+	refreshResp := suite.performRequest("POST", "/refresh", dto.RefreshTokenRequest{RefreshToken: registerResponse.RefreshToken})
+	suite.Equal(http.StatusUnauthorized, refreshResp.Code)
+
+	// Logging in during the grace period restores the account
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "leaving@example.com", Password: "Password123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	var loginResponse dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &loginResponse))
+
+	profileResp = suite.performAuthorizedRequest("GET", "/me", nil, loginResponse.AccessToken)
+	suite.Equal(http.StatusOK, profileResp.Code)
+
+	// A restored account is no longer due for deletion
+	viper.Set("account_deletion.grace_period", "0s")
+	janitor := service.NewJanitor(repository.NewPostgresBlacklistRepository(suite.db), repository.NewPostgresUserRepository(suite.db), repository.NewPostgresRefreshTokenRepository(suite.db), repository.NewPostgresSessionRepository(suite.db))
+	viper.Set("account_deletion.grace_period", "720h")
+
+	purged, err := janitor.PurgeDeletedAccounts()
+	suite.NoError(err)
+	suite.Equal(int64(0), purged)
+}
+
+func (suite *AuthIntegrationTestSuite) TestDeletedAccountIsErasedAfterGracePeriod() {
+	viper.Set("account_deletion.grace_period", "0s")
+	suite.router = suite.setupTestRouter()
+	defer func() {
+		viper.Set("account_deletion.grace_period", "720h")
+		suite.router = suite.setupTestRouter()
+	}()
+
+	registerPayload := dto.RegisterRequest{
+		Name:     "Erased User",
+		Email:    "erased@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	deleteResp := suite.performAuthorizedRequest("DELETE", "/me", dto.DeleteAccountRequest{Password: "Password123!"}, registerResponse.AccessToken)
+	suite.Equal(http.StatusOK, deleteResp.Code)
+
+	// Once the grace period is over the account can no longer be restored
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "erased@example.com", Password: "Password123!"})
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	janitor := service.NewJanitor(repository.NewPostgresBlacklistRepository(suite.db), repository.NewPostgresUserRepository(suite.db), repository.NewPostgresRefreshTokenRepository(suite.db), repository.NewPostgresSessionRepository(suite.db))
+	purged, err := janitor.PurgeDeletedAccounts()
+	suite.NoError(err)
+	suite.Equal(int64(1), purged)
+
+	// The address is free again
+	registerResp = suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLoginWithUsernameOrEmail() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "identifier",
+		Email:    "Identifier@Example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+	suite.Equal("identifier@example.com", registerResponse.User.Email)
+
+	// The same address in another case is the same account
+	duplicateResp := suite.performRequest("POST", "/register", dto.RegisterRequest{
+		Name:     "someone else",
+		Email:    "IDENTIFIER@example.com",
+		Password: "Password123!",
+	})
+	suite.Equal(http.StatusConflict, duplicateResp.Code)
+
+	// Names must be unique since they are used to log in
+	duplicateResp = suite.performRequest("POST", "/register", dto.RegisterRequest{
+		Name:     "identifier",
+		Email:    "other@example.com",
+		Password: "Password123!",
+	})
+	suite.Equal(http.StatusConflict, duplicateResp.Code)
+
+	for _, identifier := range []string{"identifier", "identifier@example.com", "IDENTIFIER@Example.COM", " identifier@example.com "} {
+		loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Identifier: identifier, Password: "Password123!"})
+		suite.Equal(http.StatusOK, loginResp.Code, identifier)
+	}
+
+	// Older clients still send the email field
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Email: "Identifier@example.com", Password: "Password123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	// Names are matched exactly
+	loginResp = suite.performRequest("POST", "/login", dto.LoginRequest{Identifier: "IDENTIFIER", Password: "Password123!"})
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	loginResp = suite.performRequest("POST", "/login", dto.LoginRequest{Identifier: "nobody", Password: "Password123!"})
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	loginResp = suite.performRequest("POST", "/login", dto.LoginRequest{Password: "Password123!"})
+	suite.Equal(http.StatusBadRequest, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestPasswordPolicy() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Policy User",
+		Email:    "policy@example.com",
+		Password: "user",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusBadRequest, registerResp.Code)
+
+	var policyResponse dto.PasswordPolicyErrorResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &policyResponse))
+	suite.Equal("password does not meet the policy", policyResponse.Error)
+
+	var rules []string
+	for _, violation := range policyResponse.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	suite.ElementsMatch([]string{"min_length", "uppercase", "digit", "special", "user_info"}, rules)
+
+	// Every character class is there, but it is still one of the first guesses
+	registerPayload.Password = "Password1!"
+	registerResp = suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusBadRequest, registerResp.Code)
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &policyResponse))
+	suite.Len(policyResponse.Violations, 1)
+	suite.Equal("strength", policyResponse.Violations[0].Rule)
+
+	// bcrypt would ignore everything after 72 bytes
+	registerPayload.Password = "Aa1!" + strings.Repeat("x", 69)
+	registerResp = suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusBadRequest, registerResp.Code)
+	suite.Contains(registerResp.Body.String(), "max_length")
+
+	registerPayload.Password = "Password123!"
+	registerResp = suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	// Changing the password goes through the same policy
+	changePayload := dto.ChangePasswordRequest{
+		CurrentPassword: "Password123!",
+		NewPassword:     "Password!",
+	}
+	changeResp := suite.performAuthorizedRequest("POST", "/me/password", changePayload, registerResponse.AccessToken)
+	suite.Equal(http.StatusBadRequest, changeResp.Code)
+	suite.Contains(changeResp.Body.String(), "digit")
+
+	// and so does resetting it, without using up the reset token
+	forgotResp := suite.performRequest("POST", "/forgot-password", dto.ForgotPasswordRequest{Email: "policy@example.com"})
+	suite.Equal(http.StatusOK, forgotResp.Code)
+	resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
+
+	resetPayload := dto.ResetPasswordRequest{
+		Token:       resetToken,
+		NewPassword: "MyPolicyUser1!",
+	}
+	resetResp := suite.performRequest("POST", "/reset-password", resetPayload)
+	suite.Equal(http.StatusBadRequest, resetResp.Code)
+	suite.Contains(resetResp.Body.String(), "user_info")
+
+	resetPayload.NewPassword = "NewPassword123!"
+	resetResp = suite.performRequest("POST", "/reset-password", resetPayload)
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLoginRehashesOutdatedPasswords() {
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	suite.NoError(err)
+
+	userRepo := repository.NewPostgresUserRepository(suite.db)
+	suite.NoError(userRepo.Create(&model.User{
+		Name:     "Legacy User",
+		Email:    "legacy@example.com",
+		Password: string(legacyHash),
+	}))
+
+	loginPayload := dto.LoginRequest{Identifier: "legacy@example.com", Password: "WrongPassword123!"}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	// A failed login leaves the hash alone
+	user, err := userRepo.FindByEmail("legacy@example.com")
+	suite.NoError(err)
+	suite.Equal(string(legacyHash), user.Password)
+
+	loginPayload.Password = "Password123!"
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	user, err = userRepo.FindByEmail("legacy@example.com")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(user.Password, "$argon2id$v=19$m=1024,t=1,p=1$"), user.Password)
+
+	// Stronger parameters are applied on the next login
+	viper.Set("password_hashing.argon2id.iterations", 2)
+	suite.router = suite.setupTestRouter()
+	defer func() {
+		viper.Set("password_hashing.argon2id.iterations", 1)
+		suite.router = suite.setupTestRouter()
+	}()
+
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	user, err = userRepo.FindByEmail("legacy@example.com")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(user.Password, "$argon2id$v=19$m=1024,t=2,p=1$"), user.Password)
+
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestLoginMigratesPasswordPepper() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Peppered User",
+		Email:    "peppered@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	userRepo := repository.NewPostgresUserRepository(suite.db)
+	user, err := userRepo.FindByEmail("peppered@example.com")
+	suite.NoError(err)
+	suite.Equal(0, user.PasswordPepperVersion)
+
+	defer func() {
+		viper.Set("password_hashing.pepper.secrets", "")
+		viper.Set("password_hashing.pepper.version", 0)
+		suite.router = suite.setupTestRouter()
+	}()
+
+	loginPayload := dto.LoginRequest{Identifier: "peppered@example.com", Password: "Password123!"}
+	for version, secrets := range []string{"", "1:first-pepper-secret", "1:first-pepper-secret,2:second-pepper-secret"} {
+		viper.Set("password_hashing.pepper.secrets", secrets)
+		viper.Set("password_hashing.pepper.version", version)
+		suite.router = suite.setupTestRouter()
+
+		// The hash made with the previous pepper is migrated on login
+		loginResp := suite.performRequest("POST", "/login", loginPayload)
+		suite.Equal(http.StatusOK, loginResp.Code)
+
+		user, err = userRepo.FindByEmail("peppered@example.com")
+		suite.NoError(err)
+		suite.Equal(version, user.PasswordPepperVersion)
+
+		loginResp = suite.performRequest("POST", "/login", loginPayload)
+		suite.Equal(http.StatusOK, loginResp.Code)
+	}
+
+	// Once migrated, the first pepper can be retired
+	viper.Set("password_hashing.pepper.secrets", "2:second-pepper-secret")
+	suite.router = suite.setupTestRouter()
+
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestPasswordHistoryPreventsReuse() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "History User",
+		Email:    "history@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	resetPassword := func(password string) *httptest.ResponseRecorder {
+		forgotResp := suite.performRequest("POST", "/forgot-password", dto.ForgotPasswordRequest{Email: "history@example.com"})
+		suite.Equal(http.StatusOK, forgotResp.Code)
+		resetToken := suite.lastEmailArgument("SendPasswordResetEmail", 1)
+
+		return suite.performRequest("POST", "/reset-password", dto.ResetPasswordRequest{
+			Token:       resetToken,
+			NewPassword: password,
+		})
+	}
+
+	// The current password cannot be set again through a reset
+	resetResp := resetPassword("Password123!")
+	suite.Equal(http.StatusBadRequest, resetResp.Code)
+	var policyError dto.PasswordPolicyErrorResponse
+	suite.NoError(json.Unmarshal(resetResp.Body.Bytes(), &policyError))
+	suite.Require().Len(policyError.Violations, 1)
+	suite.Equal("history", policyError.Violations[0].Rule)
+
+	resetResp = resetPassword("SecondPassword123!")
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+
+	loginResp := suite.performRequest("POST", "/login", dto.LoginRequest{Identifier: "history@example.com", Password: "SecondPassword123!"})
+	suite.Equal(http.StatusOK, loginResp.Code)
+	var loginResponse dto.LoginResponse
+	suite.NoError(json.Unmarshal(loginResp.Body.Bytes(), &loginResponse))
+
+	// Nor can a previous one through a change
+	changePayload := dto.ChangePasswordRequest{
+		CurrentPassword: "SecondPassword123!",
+		NewPassword:     "Password123!",
+	}
+	changeResp := suite.performAuthorizedRequest("POST", "/me/password", changePayload, loginResponse.AccessToken)
+	suite.Equal(http.StatusBadRequest, changeResp.Code)
+	suite.Contains(changeResp.Body.String(), "history")
+
+	resetResp = resetPassword("ThirdPassword123!")
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+
+	// The history keeps the size configured minus the current password
+	userRepo := repository.NewPostgresUserRepository(suite.db)
+	user, err := userRepo.FindByEmail("history@example.com")
+	suite.NoError(err)
+
+	resetResp = resetPassword("FourthPassword123!")
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+
+	history, err := userRepo.FindPasswordHistory(user.ID, 10)
+	suite.NoError(err)
+	suite.Len(history, 2)
+
+	// Passwords older than the history may be used again
+	resetResp = resetPassword("SecondPassword123!")
+	suite.Equal(http.StatusBadRequest, resetResp.Code)
+	resetResp = resetPassword("Password123!")
+	suite.Equal(http.StatusNoContent, resetResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestAccountLockout() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Locked User",
+		Email:    "locked@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	var registerResponse dto.RegisterResponse
+	suite.NoError(json.Unmarshal(registerResp.Body.Bytes(), &registerResponse))
+
+	var unlockToken string
+	suite.emailService.ExpectedCalls = nil
+	suite.emailService.On("SendAccountLockedEmail", "locked@example.com", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { unlockToken = args.String(1) }).
+		Return(nil)
+
+	wrongPayload := dto.LoginRequest{Identifier: "locked@example.com", Password: "WrongPassword123!"}
+	unknownResp := suite.performRequest("POST", "/login", dto.LoginRequest{Identifier: "unknown@example.com", Password: "WrongPassword123!"})
+	for i := 0; i < 5; i++ {
+		loginResp := suite.performRequest("POST", "/login", wrongPayload)
+		suite.Equal(http.StatusUnauthorized, loginResp.Code)
+	}
+	suite.emailService.AssertNumberOfCalls(suite.T(), "SendAccountLockedEmail", 1)
+	suite.Require().NotEmpty(unlockToken)
+
+	// A locked account answers like an unknown one, even to the right password
+	loginPayload := dto.LoginRequest{Identifier: "locked@example.com", Password: "Password123!"}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(unknownResp.Code, loginResp.Code)
+	suite.Equal(unknownResp.Body.String(), loginResp.Body.String())
+
+	unlockResp := suite.performRequest("POST", "/unlock-account", dto.UnlockAccountRequest{Token: "invalid-token"})
+	suite.Equal(http.StatusUnauthorized, unlockResp.Code)
+
+	unlockResp = suite.performRequest("POST", "/unlock-account", dto.UnlockAccountRequest{Token: unlockToken})
+	suite.Equal(http.StatusNoContent, unlockResp.Code)
+
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+
+	// The unlock token is single use
+	unlockResp = suite.performRequest("POST", "/unlock-account", dto.UnlockAccountRequest{Token: unlockToken})
+	suite.Equal(http.StatusUnauthorized, unlockResp.Code)
+
+	// Past the free attempts the account has to wait before the next check
+	viper.Set("account_lockout.base_delay", "1h")
+	defer func() {
+		viper.Set("account_lockout.base_delay", "0s")
+		suite.router = suite.setupTestRouter()
+	}()
+	suite.router = suite.setupTestRouter()
+
+	for i := 0; i < 4; i++ {
+		loginResp = suite.performRequest("POST", "/login", wrongPayload)
+		suite.Equal(http.StatusUnauthorized, loginResp.Code)
+	}
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	// Only an administrator can lift it
+	adminResp := suite.performRequest("POST", "/users/"+registerResponse.User.ID+"/unlock", nil)
+	suite.Equal(http.StatusUnauthorized, adminResp.Code)
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+
+	adminResp = suite.performAdminRequest("POST", "/users/unknown-id/unlock", suite.config.AdminAPIKey)
+	suite.Equal(http.StatusNotFound, adminResp.Code)
+	adminResp = suite.performAdminRequest("POST", "/users/"+registerResponse.User.ID+"/unlock", suite.config.AdminAPIKey)
+	suite.Equal(http.StatusNoContent, adminResp.Code)
+
+	loginResp = suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusOK, loginResp.Code)
+}
+
+func (suite *AuthIntegrationTestSuite) TestConcurrentFailedLoginsRespectLockout() {
+	registerPayload := dto.RegisterRequest{
+		Name:     "Guessed User",
+		Email:    "guessed@example.com",
+		Password: "Password123!",
+	}
+	registerResp := suite.performRequest("POST", "/register", registerPayload)
+	suite.Equal(http.StatusCreated, registerResp.Code)
+
+	// Every attempt is counted before its password is checked, so parallel
+	// guesses cannot check more passwords than account_lockout.max_attempts
+	wrongPayload := dto.LoginRequest{Identifier: "guessed@example.com", Password: "WrongPassword123!"}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			suite.performRequest("POST", "/login", wrongPayload)
+		}()
+	}
+	wg.Wait()
+
+	user, err := repository.NewPostgresUserRepository(suite.db).FindByEmail("guessed@example.com")
+	suite.Require().NoError(err)
+	suite.LessOrEqual(user.FailedLoginAttempts, 5)
+
+	for user.FailedLoginAttempts < 5 {
+		suite.performRequest("POST", "/login", wrongPayload)
+		user, err = repository.NewPostgresUserRepository(suite.db).FindByEmail("guessed@example.com")
+		suite.Require().NoError(err)
+	}
+
+	loginPayload := dto.LoginRequest{Identifier: "guessed@example.com", Password: "Password123!"}
+	loginResp := suite.performRequest("POST", "/login", loginPayload)
+	suite.Equal(http.StatusUnauthorized, loginResp.Code)
+}
+
+// --- Pirvate Method ---
+func (suite *AuthIntegrationTestSuite) performRequest(method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body []byte
+	if payload != nil {
+		body, _ = json.Marshal(payload)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *AuthIntegrationTestSuite) performAuthorizedRequest(method, path string, payload interface{}, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+
+	if payload != nil {
+		body, _ := json.Marshal(payload)
+		req.Body = io.NopCloser(bytes.NewBuffer(body))
+	}
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *AuthIntegrationTestSuite) performAdminRequest(method, path, adminKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Admin-Key", adminKey)
+
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+// lastEmailArgument returns an argument of the latest call to a method of the
+// email service, where the tests find the tokens that are only mailed.
+func (suite *AuthIntegrationTestSuite) lastEmailArgument(method string, index int) string {
+	calls := suite.emailService.Calls
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Method == method {
+			return calls[i].Arguments.String(index)
+		}
+	}
+	suite.T().Fatalf("%s was not called", method)
+	return ""
 }
 
 // --- End Pirvate Method ---
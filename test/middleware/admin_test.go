@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/YoubaImkf/go-auth-api/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	testCases := map[string]struct {
+		configuredKey string
+		providedKey   string
+		expected      int
+	}{
+		"right key":     {configuredKey: "admin-key", providedKey: "admin-key", expected: http.StatusNoContent},
+		"wrong key":     {configuredKey: "admin-key", providedKey: "other-key", expected: http.StatusUnauthorized},
+		"missing key":   {configuredKey: "admin-key", providedKey: "", expected: http.StatusUnauthorized},
+		"no key set up": {configuredKey: "", providedKey: "", expected: http.StatusForbidden},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			viper.Set("admin.api_key", tc.configuredKey)
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/admin", middleware.AdminMiddleware(), func(ctx *gin.Context) {
+				ctx.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest("POST", "/admin", nil)
+			if tc.providedKey != "" {
+				req.Header.Set("X-Admin-Key", tc.providedKey)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/YoubaImkf/go-auth-api/internal/dto"
+	"github.com/YoubaImkf/go-auth-api/internal/middleware"
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddlewarePerIP(t *testing.T) {
+	setRateLimitConfig("ip", 2)
+	router := newRateLimitedRouter()
+
+	for i := 1; i >= 0; i-- {
+		resp := performLogin(t, router, "192.0.2.1", "user@example.com")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(i), resp.Header().Get("RateLimit-Remaining"))
+	}
+
+	resp := performLogin(t, router, "192.0.2.1", "other@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", resp.Header().Get("RateLimit-Reset"))
+
+	resp = performLogin(t, router, "192.0.2.2", "user@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRateLimitMiddlewarePerEmail(t *testing.T) {
+	setRateLimitConfig("email", 1)
+	router := newRateLimitedRouter()
+
+	resp := performLogin(t, router, "192.0.2.1", "user@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	// The address is normalised, changing the IP does not help
+	resp = performLogin(t, router, "192.0.2.2", " User@Example.com")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+
+	resp = performLogin(t, router, "192.0.2.1", "other@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRateLimitMiddlewarePerRoute(t *testing.T) {
+	setRateLimitConfig("route", 1)
+	router := newRateLimitedRouter()
+
+	resp := performLogin(t, router, "192.0.2.1", "user@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = performLogin(t, router, "192.0.2.2", "other@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+}
+
+func TestRateLimitMiddlewareRefusedRequestsSpareOtherBuckets(t *testing.T) {
+	setRateLimitConfig("ip", 2)
+	viper.Set("rate_limit.routes.login.email.burst", 3)
+	viper.Set("rate_limit.routes.login.route.burst", 3)
+	router := newRateLimitedRouter()
+
+	// A client over its own limit does not use up the shared route bucket
+	for i := range 10 {
+		resp := performLogin(t, router, "192.0.2.1", "user@example.com")
+		if i < 2 {
+			assert.Equal(t, http.StatusOK, resp.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		}
+	}
+
+	resp := performLogin(t, router, "192.0.2.2", "user@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+
+	// Tokens taken before a later bucket refused are given back
+	viper.Set("rate_limit.routes.login.email.burst", 1)
+	viper.Set("rate_limit.routes.login.route.burst", 10)
+	router = newRateLimitedRouter()
+
+	resp = performLogin(t, router, "192.0.2.3", "user@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = performLogin(t, router, "192.0.2.3", "user@example.com")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	resp = performLogin(t, router, "192.0.2.3", "other@example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRateLimitMiddlewareWithoutLimits(t *testing.T) {
+	setRateLimitConfig("ip", 0)
+	router := newRateLimitedRouter()
+
+	for range 10 {
+		resp := performLogin(t, router, "192.0.2.1", "user@example.com")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	}
+}
+
+// newRateLimitedRouter serves a login route that echoes the identifier it
+// receives, to check that the limiter leaves the body in place.
+func newRateLimitedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", middleware.RateLimitMiddleware(repository.NewMemoryRateLimitStore(), "login"), func(ctx *gin.Context) {
+		var loginRequest dto.LoginRequest
+		if err := ctx.ShouldBindJSON(&loginRequest); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"identifier": loginRequest.Identifier})
+	})
+	return router
+}
+
+func performLogin(t *testing.T, router *gin.Engine, ip, identifier string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto.LoginRequest{Identifier: identifier, Password: "Password123!"})
+	req := httptest.NewRequest("POST", "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":1234"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Equal(t, identifier, response["identifier"], "the request body reaches the handler")
+	}
+	return w
+}
+
+// setRateLimitConfig limits the login route to burst requests per minute in
+// the given scope only.
+func setRateLimitConfig(scope string, burst int) {
+	for _, s := range []string{"ip", "email", "route"} {
+		viper.Set("rate_limit.routes.login."+s+".burst", 0)
+		viper.Set("rate_limit.routes.login."+s+".period", "1m")
+	}
+	viper.Set("rate_limit.routes.login."+scope+".burst", burst)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingBlacklistRepository struct {
+	entries map[string]time.Time
+	lookups int
+}
+
+func newCountingBlacklistRepository() *countingBlacklistRepository {
+	return &countingBlacklistRepository{entries: map[string]time.Time{}}
+}
+
+func (r *countingBlacklistRepository) Add(token string, expiry time.Time) error {
+	r.entries[token] = expiry
+	return nil
+}
+
+func (r *countingBlacklistRepository) IsBlacklisted(token string) bool {
+	expiry, err := r.FindExpiry(token)
+	return err == nil && time.Now().Before(expiry)
+}
+
+func (r *countingBlacklistRepository) FindExpiry(token string) (time.Time, error) {
+	r.lookups++
+	expiry, ok := r.entries[token]
+	if !ok {
+		return time.Time{}, errors.New("token is not blacklisted")
+	}
+	return expiry, nil
+}
+
+func (r *countingBlacklistRepository) ActiveJTIs() ([]string, error) {
+	tokens := []string{}
+	for token, expiry := range r.entries {
+		if time.Now().Before(expiry) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *countingBlacklistRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func TestCachedBlacklistSkipsStorageForUnknownTokens(t *testing.T) {
+	next := newCountingBlacklistRepository()
+	cache := repository.NewCachedBlacklistRepository(next, 10, time.Minute, time.Hour)
+
+	for range 100 {
+		assert.False(t, cache.IsBlacklisted("never-blacklisted"))
+	}
+	assert.LessOrEqual(t, next.lookups, 1)
+}
+
+func TestCachedBlacklistServesPositiveHitsFromMemory(t *testing.T) {
+	next := newCountingBlacklistRepository()
+	next.entries["existing"] = time.Now().Add(time.Hour)
+	cache := repository.NewCachedBlacklistRepository(next, 10, time.Minute, time.Hour)
+
+	require.NoError(t, cache.Add("added", time.Now().Add(time.Hour)))
+	assert.True(t, cache.IsBlacklisted("added"))
+	assert.Equal(t, 0, next.lookups)
+
+	assert.True(t, cache.IsBlacklisted("existing"))
+	assert.True(t, cache.IsBlacklisted("existing"))
+	assert.Equal(t, 1, next.lookups)
+}
+
+func TestCachedBlacklistEntriesExpireWithTheToken(t *testing.T) {
+	next := newCountingBlacklistRepository()
+	cache := repository.NewCachedBlacklistRepository(next, 10, time.Hour, time.Hour)
+
+	require.NoError(t, cache.Add("short-lived", time.Now().Add(20*time.Millisecond)))
+	assert.True(t, cache.IsBlacklisted("short-lived"))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, cache.IsBlacklisted("short-lived"))
+}
+
+func TestCachedBlacklistEvictsLeastRecentlyUsed(t *testing.T) {
+	next := newCountingBlacklistRepository()
+	cache := repository.NewCachedBlacklistRepository(next, 1, time.Hour, time.Hour)
+
+	require.NoError(t, cache.Add("first", time.Now().Add(time.Hour)))
+	require.NoError(t, cache.Add("second", time.Now().Add(time.Hour)))
+
+	assert.True(t, cache.IsBlacklisted("first"))
+	assert.Equal(t, 1, next.lookups)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSessionRepository implements the revocation methods only, the cache
+// does not call the others.
+type countingSessionRepository struct {
+	repository.SessionRepository
+	sessions map[string]*model.Session
+	lookups  int
+}
+
+func (r *countingSessionRepository) IsRevoked(id string, userID uint) (bool, error) {
+	r.lookups++
+	session, ok := r.sessions[id]
+	if !ok {
+		return true, nil
+	}
+	return session.UserID != userID || session.RevokedAt != nil, nil
+}
+
+func (r *countingSessionRepository) Revoke(id string) error {
+	now := time.Now()
+	r.sessions[id].RevokedAt = &now
+	return nil
+}
+
+func TestCachedSessionServesStatesFromMemory(t *testing.T) {
+	next := &countingSessionRepository{sessions: map[string]*model.Session{"session": {ID: "session", UserID: 1}}}
+	cache := repository.NewCachedSessionRepository(next, 10, time.Minute)
+
+	for range 10 {
+		revoked, err := cache.IsRevoked("session", 1)
+		require.NoError(t, err)
+		assert.False(t, revoked)
+	}
+	assert.Equal(t, 1, next.lookups)
+
+	// A cached state is not reused for another user
+	revoked, err := cache.IsRevoked("session", 2)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.Equal(t, 2, next.lookups)
+}
+
+func TestCachedSessionSeesRevocationAtOnce(t *testing.T) {
+	next := &countingSessionRepository{sessions: map[string]*model.Session{"session": {ID: "session", UserID: 1}}}
+	cache := repository.NewCachedSessionRepository(next, 10, time.Minute)
+
+	revoked, err := cache.IsRevoked("session", 1)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, cache.Revoke("session"))
+
+	revoked, err = cache.IsRevoked("session", 1)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
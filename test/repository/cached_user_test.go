@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingUserRepository implements the cut-off methods only, the cache does
+// not call the others.
+type countingUserRepository struct {
+	repository.UserRepository
+	users   map[string]*model.User
+	lookups int
+}
+
+func (r *countingUserRepository) FindTokenCutoff(uuid string) (*model.User, error) {
+	r.lookups++
+	user, ok := r.users[uuid]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *countingUserRepository) SetTokensValidAfter(userID uint, validAfter time.Time) error {
+	for _, user := range r.users {
+		if user.ID == userID {
+			user.TokensValidAfter = &validAfter
+		}
+	}
+	return nil
+}
+
+func TestCachedUserServesCutoffsFromMemory(t *testing.T) {
+	next := &countingUserRepository{users: map[string]*model.User{"user-uuid": {ID: 1, UUID: "user-uuid"}}}
+	cache := repository.NewCachedUserRepository(next, 10, time.Minute)
+
+	for range 10 {
+		user, err := cache.FindTokenCutoff("user-uuid")
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), user.ID)
+	}
+	assert.Equal(t, 1, next.lookups)
+
+	// Unknown users are not cached
+	_, err := cache.FindTokenCutoff("unknown-uuid")
+	assert.Error(t, err)
+	_, err = cache.FindTokenCutoff("unknown-uuid")
+	assert.Error(t, err)
+	assert.Equal(t, 3, next.lookups)
+}
+
+func TestCachedUserForgetsCutoffOnRevoke(t *testing.T) {
+	next := &countingUserRepository{users: map[string]*model.User{"user-uuid": {ID: 1, UUID: "user-uuid"}}}
+	cache := repository.NewCachedUserRepository(next, 10, time.Minute)
+
+	user, err := cache.FindTokenCutoff("user-uuid")
+	require.NoError(t, err)
+	assert.Nil(t, user.TokensValidAfter)
+
+	validAfter := time.Now()
+	require.NoError(t, cache.SetTokensValidAfter(1, validAfter))
+
+	user, err = cache.FindTokenCutoff("user-uuid")
+	require.NoError(t, err)
+	require.NotNil(t, user.TokensValidAfter)
+	assert.True(t, validAfter.Equal(*user.TokensValidAfter))
+}
+
+func TestCachedUserEntriesExpireAfterTTL(t *testing.T) {
+	next := &countingUserRepository{users: map[string]*model.User{"user-uuid": {ID: 1, UUID: "user-uuid"}}}
+	cache := repository.NewCachedUserRepository(next, 10, 20*time.Millisecond)
+
+	_, err := cache.FindTokenCutoff("user-uuid")
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.FindTokenCutoff("user-uuid")
+	require.NoError(t, err)
+	assert.Equal(t, 2, next.lookups)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateLimitStoreRefillsOverPeriod(t *testing.T) {
+	store := repository.NewMemoryRateLimitStore()
+	now := time.Now()
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take("login:ip:192.0.2.1", 3, time.Minute, now)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take("login:ip:192.0.2.1", 3, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	// Other keys have their own bucket
+	result, err = store.Take("login:ip:192.0.2.2", 3, time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// One token comes back every period / burst
+	result, err = store.Take("login:ip:192.0.2.1", 3, time.Minute, now.Add(20*time.Second))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	// The bucket never holds more than burst tokens
+	result, err = store.Take("login:ip:192.0.2.1", 3, time.Minute, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Remaining)
+	assert.Equal(t, 20*time.Second, result.Reset)
+}
+
+func TestMemoryRateLimitStoreRefund(t *testing.T) {
+	store := repository.NewMemoryRateLimitStore()
+	now := time.Now()
+
+	_, err := store.Take("login:route:all", 1, time.Minute, now)
+	require.NoError(t, err)
+	require.NoError(t, store.Refund("login:route:all", 1, time.Minute, now))
+
+	result, err := store.Take("login:route:all", 1, time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Refunds never fill a bucket beyond burst
+	require.NoError(t, store.Refund("login:route:all", 1, time.Minute, now))
+	require.NoError(t, store.Refund("login:route:all", 1, time.Minute, now))
+
+	result, err = store.Take("login:route:all", 1, time.Minute, now)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = store.Take("login:route:all", 1, time.Minute, now)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
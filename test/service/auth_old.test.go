@@ -29,6 +29,36 @@ func (m *MockEmailService) SendPasswordResetEmail(to, token string) error {
 	return args.Error(0)
 }
 
+func (m *MockEmailService) SendVerificationEmail(to, token string) error {
+	args := m.Called(to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendPasswordChangedEmail(to string) error {
+	args := m.Called(to)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendEmailChangeConfirmation(to, token string) error {
+	args := m.Called(to, token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendEmailChangedNotice(to, newEmail, undoToken string) error {
+	args := m.Called(to, newEmail, undoToken)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendAccountDeletionEmail(to string, deletionDate time.Time) error {
+	args := m.Called(to, deletionDate)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) SendAccountLockedEmail(to, token string, lockedUntil time.Time) error {
+	args := m.Called(to, token, lockedUntil)
+	return args.Error(0)
+}
+
 type AuthServiceTestSuite struct {
 	suite.Suite
 	db            *gorm.DB
@@ -62,7 +92,7 @@ func (suite *AuthServiceTestSuite) TestRegister() {
 		Password: "Password123!",
 	}
 
-	user, accessToken, refreshToken, err := suite.authService.Register(registerRequest)
+	user, accessToken, refreshToken, err := suite.authService.Register(registerRequest, dto.ClientInfo{})
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), user)
@@ -94,7 +124,7 @@ func (suite *AuthServiceTestSuite) TestRegisterExistingUser() {
 		Password: "Password123!",
 	}
 
-	user, accessToken, refreshToken, err := suite.authService.Register(registerRequest)
+	user, accessToken, refreshToken, err := suite.authService.Register(registerRequest, dto.ClientInfo{})
 
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "user already exists", err.Error())
@@ -144,7 +174,7 @@ func (suite *AuthServiceTestSuite) TestRegisterExistingUser() {
 // 				Password: tc.password,
 // 			}
 
-// 			user, accessToken, refreshToken, err := suite.authService.Register(registerRequest)
+// 			user, accessToken, refreshToken, err := suite.authService.Register(registerRequest, dto.ClientInfo{})
 
 // 			assert.Error(t, err)
 // 			assert.Equal(t, tc.wantErr, err.Error())
@@ -169,7 +199,7 @@ func (suite *AuthServiceTestSuite) TestLogin() {
 		Password: "Password123!",
 	}
 
-	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest)
+	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest, dto.ClientInfo{})
 
 	assert.NoError(suite.T(), err)
 	assert.NotNil(suite.T(), loggedInUser)
@@ -193,7 +223,7 @@ func (suite *AuthServiceTestSuite) TestLoginInvalidCredentials() {
 		Password: "WrongPassword123!",
 	}
 
-	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest)
+	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest, dto.ClientInfo{})
 
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "invalid credentials", err.Error())
@@ -208,7 +238,7 @@ func (suite *AuthServiceTestSuite) TestLoginNonExistentUser() {
 		Password: "Password123!",
 	}
 
-	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest)
+	loggedInUser, accessToken, refreshToken, err := suite.authService.Login(loginRequest, dto.ClientInfo{})
 
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), loggedInUser)
@@ -223,9 +253,12 @@ func (suite *AuthServiceTestSuite) TestForgotPassword() {
 	}
 	suite.userRepo.Create(user)
 
-	suite.emailService.On("SendPasswordResetEmail", "john.doe@example.com", mock.Anything).Return(nil)
+	var token string
+	suite.emailService.On("SendPasswordResetEmail", "john.doe@example.com", mock.Anything).
+		Run(func(args mock.Arguments) { token = args.String(1) }).
+		Return(nil)
 
-	token, err := suite.authService.ForgotPassword("john.doe@example.com")
+	err := suite.authService.ForgotPassword("john.doe@example.com")
 
 	assert.NoError(suite.T(), err)
 	assert.NotEmpty(suite.T(), token)
@@ -240,11 +273,9 @@ func (suite *AuthServiceTestSuite) TestForgotPassword() {
 }
 
 func (suite *AuthServiceTestSuite) TestForgotPasswordUserNotFound() {
-	token, err := suite.authService.ForgotPassword("nonexistent@example.com")
+	err := suite.authService.ForgotPassword("nonexistent@example.com")
 
-	assert.Error(suite.T(), err)
-	assert.Empty(suite.T(), token)
-	assert.Equal(suite.T(), "user not found", err.Error())
+	assert.NoError(suite.T(), err)
 	suite.emailService.AssertNotCalled(suite.T(), "SendPasswordResetEmail")
 }
 
@@ -336,7 +367,7 @@ func (suite *AuthServiceTestSuite) connectToDatabase(host, port, user, password,
 }
 
 func (suite *AuthServiceTestSuite) migrateDatabase() {
-	suite.db.AutoMigrate(&model.User{}, &model.PasswordReset{})
+	suite.db.AutoMigrate(&model.User{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.EmailChange{}, &model.PasswordHistory{}, &model.AccountUnlock{})
 }
 
 func (suite *AuthServiceTestSuite) initializeRepositories() {
@@ -345,10 +376,26 @@ func (suite *AuthServiceTestSuite) initializeRepositories() {
 }
 
 func (suite *AuthServiceTestSuite) initializeServices() {
+	tokenService, err := service.NewTokenService()
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
+	passwordHasher, err := service.NewPasswordHasher()
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+
 	suite.emailService = new(MockEmailService)
+	suite.emailService.On("SendVerificationEmail", mock.Anything, mock.Anything).Return(nil)
 	suite.authService = service.NewAuthService(
 		suite.userRepo,
 		suite.blacklistRepo,
+		repository.NewPostgresRefreshTokenRepository(suite.db),
+		repository.NewPostgresSessionRepository(suite.db),
 		suite.emailService,
+		tokenService,
+		service.NewPasswordPolicy(nil),
+		passwordHasher,
 	)
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreachedPasswordIndex(t *testing.T) {
+	dir := t.TempDir()
+	source := writePwnedPasswords(t, dir, map[string]int{
+		"Password123!": 120000,
+		"hunter2":      17000,
+		"rarely seen":  1,
+	})
+	indexPath := filepath.Join(dir, "breached.idx")
+
+	written, err := service.BuildBreachedPasswordIndex(source, indexPath, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, written)
+
+	index, err := service.OpenBreachedPasswordIndex(indexPath)
+	require.NoError(t, err)
+	defer index.Close()
+
+	for password, breached := range map[string]bool{
+		"Password123!": true,
+		"hunter2":      true,
+		"rarely seen":  false,
+		"xK9#mQ2$vL":   false,
+	} {
+		got, err := index.IsBreached(password)
+		require.NoError(t, err)
+		assert.Equal(t, breached, got, password)
+	}
+}
+
+func TestBreachedPasswordIndexRequiresOrderedSource(t *testing.T) {
+	dir := t.TempDir()
+	hashes := []string{sha1Hex("first"), sha1Hex("second")}
+	sort.Sort(sort.Reverse(sort.StringSlice(hashes)))
+
+	source := filepath.Join(dir, "pwned.txt")
+	require.NoError(t, os.WriteFile(source, []byte(hashes[0]+":1\n"+hashes[1]+":1\n"), 0600))
+
+	_, err := service.BuildBreachedPasswordIndex(source, filepath.Join(dir, "breached.idx"), 1)
+	assert.ErrorContains(t, err, "not ordered by hash")
+}
+
+func TestPasswordPolicyRejectsBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "breached.idx")
+	_, err := service.BuildBreachedPasswordIndex(writePwnedPasswords(t, dir, map[string]int{"Tr0ub4dor&3": 42}), indexPath, 1)
+	require.NoError(t, err)
+
+	index, err := service.OpenBreachedPasswordIndex(indexPath)
+	require.NoError(t, err)
+	defer index.Close()
+
+	setPasswordPolicyConfig()
+	policy := service.NewPasswordPolicy(index)
+
+	assert.NoError(t, policy.Validate("xK9#mQ2$vL"))
+
+	err = policy.Validate("Tr0ub4dor&3")
+	require.Error(t, err)
+	policyErr, ok := err.(*service.PasswordPolicyError)
+	require.True(t, ok)
+	require.Len(t, policyErr.Violations, 1)
+	assert.Equal(t, "breached", policyErr.Violations[0].Rule)
+}
+
+// writePwnedPasswords writes passwords in the format of the Have I Been Pwned
+// download, ordered by hash.
+func writePwnedPasswords(t *testing.T, dir string, counts map[string]int) string {
+	var lines []string
+	for password, count := range counts {
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(sha1Hex(password)), count))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(dir, "pwned.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0600))
+	return path
+}
+
+func sha1Hex(password string) string {
+	hash := sha1.Sum([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/repository"
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The purge fakes implement the delete methods only, the janitor does not
+// call the others.
+type purgeBlacklistRepository struct {
+	repository.BlacklistRepository
+	err error
+}
+
+func (r *purgeBlacklistRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	return 0, r.err
+}
+
+type purgeUserRepository struct {
+	repository.UserRepository
+	mu     sync.Mutex
+	purges int
+}
+
+func (r *purgeUserRepository) DeleteExpiredResetTokens(before time.Time, limit int) (int64, error) {
+	return 1, nil
+}
+
+func (r *purgeUserRepository) DeleteExpiredVerificationTokens(before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (r *purgeUserRepository) DeleteExpiredEmailChanges(before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (r *purgeUserRepository) DeleteExpiredUnlockTokens(before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (r *purgeUserRepository) PurgeDeletedUsers(before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.purges++
+	return 0, nil
+}
+
+func (r *purgeUserRepository) purgeCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.purges
+}
+
+type purgeRefreshTokenRepository struct {
+	repository.RefreshTokenRepository
+}
+
+func (r *purgeRefreshTokenRepository) DeleteExpired(before time.Time, limit int) (int64, error) {
+	return 2, nil
+}
+
+type purgeSessionRepository struct {
+	repository.SessionRepository
+	err error
+}
+
+func (r *purgeSessionRepository) DeleteInactive(before time.Time, limit int) (int64, error) {
+	return 0, r.err
+}
+
+func TestJanitorKeepsPurgingAfterATableFails(t *testing.T) {
+	viper.Set("janitor.batch_size", 10)
+	defer viper.Set("janitor.batch_size", nil)
+
+	blacklistErr := errors.New("blacklist unavailable")
+	sessionErr := errors.New("sessions unavailable")
+	janitor := service.NewJanitor(
+		&purgeBlacklistRepository{err: blacklistErr},
+		&purgeUserRepository{},
+		&purgeRefreshTokenRepository{},
+		&purgeSessionRepository{err: sessionErr},
+	)
+
+	result, err := janitor.Purge()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, blacklistErr)
+	assert.ErrorIs(t, err, sessionErr)
+
+	// The tables after the failing one were still purged
+	assert.Equal(t, int64(1), result.PasswordResets)
+	assert.Equal(t, int64(2), result.RefreshTokens)
+}
+
+func TestJanitorPurgesWhenStarted(t *testing.T) {
+	viper.Set("janitor.interval", time.Hour)
+	defer viper.Set("janitor.interval", nil)
+
+	userRepo := &purgeUserRepository{}
+	janitor := service.NewJanitor(
+		&purgeBlacklistRepository{},
+		userRepo,
+		&purgeRefreshTokenRepository{},
+		&purgeSessionRepository{},
+	)
+
+	janitor.Start()
+	defer janitor.Stop()
+
+	assert.Eventually(t, func() bool { return userRepo.purgeCount() == 1 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateSigningKeysKeepsPreviousKeyValid(t *testing.T) {
+	setTokenConfig()
+	keyRingPath := t.TempDir()
+
+	// Token signed with the configured secret before the key ring existed
+	legacyService, err := service.NewTokenService()
+	require.NoError(t, err)
+	legacyToken, _, err := legacyService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	viper.Set("jwt.keyring_path", keyRingPath)
+
+	firstKeyID := rotateSigningKeys(t, keyRingPath, "EdDSA", 24*time.Hour)
+
+	firstService, err := service.NewTokenService()
+	require.NoError(t, err)
+	firstToken, _, err := firstService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	secondKeyID := rotateSigningKeys(t, keyRingPath, "ES256", 24*time.Hour)
+	assert.NotEqual(t, firstKeyID, secondKeyID)
+
+	secondService, err := service.NewTokenService()
+	require.NoError(t, err)
+
+	_, err = secondService.ParseToken(legacyToken, service.AccessTokenType)
+	assert.NoError(t, err)
+	_, err = secondService.ParseToken(firstToken, service.AccessTokenType)
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{firstKeyID, secondKeyID}, jwksKeyIDs(secondService))
+}
+
+func TestRotateSigningKeysRetiresOldKeys(t *testing.T) {
+	setTokenConfig()
+	keyRingPath := t.TempDir()
+	viper.Set("jwt.keyring_path", keyRingPath)
+
+	rotateSigningKeys(t, keyRingPath, "EdDSA", time.Millisecond)
+
+	firstService, err := service.NewTokenService()
+	require.NoError(t, err)
+	firstToken, _, err := firstService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	rotateSigningKeys(t, keyRingPath, "EdDSA", time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	secondService, err := service.NewTokenService()
+	require.NoError(t, err)
+
+	_, err = secondService.ParseToken(firstToken, service.AccessTokenType)
+	assert.Error(t, err)
+	assert.Len(t, secondService.JWKS().Keys, 1)
+}
+
+func TestNextSigningKeyVerifiesBeforeItSigns(t *testing.T) {
+	setTokenConfig()
+	keyRingPath := t.TempDir()
+	viper.Set("jwt.keyring_path", keyRingPath)
+
+	activeKeyID := rotateSigningKeys(t, keyRingPath, "EdDSA", 24*time.Hour)
+
+	nextKeyID, err := service.AddSigningKey(keyRingPath, "EdDSA")
+	require.NoError(t, err)
+
+	_, err = service.AddSigningKey(keyRingPath, "EdDSA")
+	assert.Error(t, err, "only one key waits to be promoted at a time")
+
+	// An instance that loaded the next key publishes it but keeps signing
+	// with the active one
+	beforePromotion, err := service.NewTokenService()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{activeKeyID, nextKeyID}, jwksKeyIDs(beforePromotion))
+	oldToken, _, err := beforePromotion.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	promotedKeyID, err := service.PromoteSigningKey(keyRingPath, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, nextKeyID, promotedKeyID)
+
+	_, err = service.PromoteSigningKey(keyRingPath, 24*time.Hour)
+	assert.Error(t, err)
+
+	// Instances on either side of the promotion accept each other's tokens
+	afterPromotion, err := service.NewTokenService()
+	require.NoError(t, err)
+	newToken, _, err := afterPromotion.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	_, err = beforePromotion.ParseToken(newToken, service.AccessTokenType)
+	assert.NoError(t, err)
+	_, err = afterPromotion.ParseToken(oldToken, service.AccessTokenType)
+	assert.NoError(t, err)
+}
+
+func TestTokenServiceReloadsTheKeyRing(t *testing.T) {
+	setTokenConfig()
+	keyRingPath := t.TempDir()
+	viper.Set("jwt.keyring_path", keyRingPath)
+	viper.Set("jwt.keyring_reload_interval", "1ms")
+
+	activeKeyID := rotateSigningKeys(t, keyRingPath, "EdDSA", 24*time.Hour)
+
+	tokenService, err := service.NewTokenService()
+	require.NoError(t, err)
+	assert.Equal(t, []string{activeKeyID}, jwksKeyIDs(tokenService))
+
+	nextKeyID, err := service.AddSigningKey(keyRingPath, "EdDSA")
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(jwksKeyIDs(tokenService)) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.ElementsMatch(t, []string{activeKeyID, nextKeyID}, jwksKeyIDs(tokenService))
+}
+
+// rotateSigningKeys adds a key and promotes it at once, as a single instance
+// deployment can.
+func rotateSigningKeys(t *testing.T, keyRingPath, algorithm string, retireAfter time.Duration) string {
+	t.Helper()
+
+	keyID, err := service.AddSigningKey(keyRingPath, algorithm)
+	require.NoError(t, err)
+	promotedKeyID, err := service.PromoteSigningKey(keyRingPath, retireAfter)
+	require.NoError(t, err)
+	require.Equal(t, keyID, promotedKeyID)
+	return keyID
+}
+
+func jwksKeyIDs(tokenService *service.TokenService) []string {
+	kids := []string{}
+	for _, key := range tokenService.JWKS().Keys {
+		kids = append(kids, key.Kid)
+	}
+	return kids
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasherArgon2id(t *testing.T) {
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	hasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+
+	hash, pepperVersion, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.Equal(t, 0, pepperVersion)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+	assert.Len(t, strings.Split(hash, "$"), 6)
+
+	other, _, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "every hash gets its own salt")
+
+	ok, err := hasher.Verify(hash, 0, "Password123!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = hasher.Verify(hash, 0, "Password123?")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, hasher.NeedsRehash(hash, 0))
+
+	viper.Set("password_hashing.argon2id.memory", 2048)
+	stronger, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(hash, 0))
+
+	// Hashes made with older parameters still verify
+	ok, err = stronger.Verify(hash, 0, "Password123!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestPasswordHasherLegacyBcrypt(t *testing.T) {
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("Password123!"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	hasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+
+	ok, err := hasher.Verify(string(legacyHash), 0, "Password123!")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = hasher.Verify(string(legacyHash), 0, "Password123?")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.True(t, hasher.NeedsRehash(string(legacyHash), 0))
+
+	setPasswordHashingConfig(service.HashAlgorithmBcrypt)
+	bcryptHasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+	assert.True(t, bcryptHasher.NeedsRehash(string(legacyHash), 0), "cost is below the configured one")
+
+	hash, _, err := bcryptHasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.False(t, bcryptHasher.NeedsRehash(hash, 0))
+}
+
+func TestPasswordHasherConfiguration(t *testing.T) {
+	setPasswordHashingConfig("md5")
+	_, err := service.NewPasswordHasher()
+	assert.Error(t, err)
+
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	viper.Set("password_hashing.argon2id.iterations", 0)
+	_, err = service.NewPasswordHasher()
+	assert.Error(t, err)
+
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	hasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+
+	_, err = hasher.Verify("$argon2id$v=19$m=1024$salt", 0, "Password123!")
+	assert.Error(t, err)
+
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	viper.Set("password_hashing.argon2id.key_length", 4)
+	_, err = service.NewPasswordHasher()
+	assert.Error(t, err)
+}
+
+func TestPasswordHasherRejectsMalformedArgon2id(t *testing.T) {
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	hasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+
+	hash, _, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	parts := strings.Split(hash, "$")
+
+	malformed := map[string]string{
+		"empty key":      strings.Join(append(parts[:5:5], ""), "$"),
+		"short key":      strings.Join(append(parts[:5:5], "AAAA"), "$"),
+		"empty salt":     strings.Join([]string{"", parts[1], parts[2], parts[3], "", parts[5]}, "$"),
+		"no iterations":  strings.Join([]string{"", parts[1], parts[2], "m=1024,t=0,p=1", parts[4], parts[5]}, "$"),
+		"no parallelism": strings.Join([]string{"", parts[1], parts[2], "m=1024,t=1,p=0", parts[4], parts[5]}, "$"),
+	}
+	for name, encoded := range malformed {
+		ok, err := hasher.Verify(encoded, 0, "anything")
+		assert.Error(t, err, name)
+		assert.False(t, ok, name)
+		assert.True(t, hasher.NeedsRehash(encoded, 0), name)
+	}
+}
+
+func TestPasswordHasherPepper(t *testing.T) {
+	for _, algorithm := range []string{service.HashAlgorithmArgon2id, service.HashAlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			setPasswordHashingConfig(algorithm)
+			unpeppered, err := service.NewPasswordHasher()
+			require.NoError(t, err)
+			legacyHash, _, err := unpeppered.Hash("Password123!")
+			require.NoError(t, err)
+
+			viper.Set("password_hashing.pepper.secrets", "1:first-pepper-secret")
+			viper.Set("password_hashing.pepper.version", 1)
+			hasher, err := service.NewPasswordHasher()
+			require.NoError(t, err)
+
+			hash, pepperVersion, err := hasher.Hash("Password123!")
+			require.NoError(t, err)
+			assert.Equal(t, 1, pepperVersion)
+
+			ok, err := hasher.Verify(hash, 1, "Password123!")
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			// Without the pepper the stored hash is useless
+			ok, err = unpeppered.Verify(hash, 0, "Password123!")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			// Hashes made before the pepper was introduced still verify
+			ok, err = hasher.Verify(legacyHash, 0, "Password123!")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.True(t, hasher.NeedsRehash(legacyHash, 0))
+			assert.False(t, hasher.NeedsRehash(hash, 1))
+
+			// After a rotation the previous pepper still verifies until it is removed
+			viper.Set("password_hashing.pepper.secrets", "1:first-pepper-secret,2:second-pepper-secret")
+			viper.Set("password_hashing.pepper.version", 2)
+			rotated, err := service.NewPasswordHasher()
+			require.NoError(t, err)
+
+			ok, err = rotated.Verify(hash, 1, "Password123!")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.True(t, rotated.NeedsRehash(hash, 1))
+
+			viper.Set("password_hashing.pepper.secrets", "2:second-pepper-secret")
+			retired, err := service.NewPasswordHasher()
+			require.NoError(t, err)
+
+			_, err = retired.Verify(hash, 1, "Password123!")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPasswordHasherPepperConfiguration(t *testing.T) {
+	setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+	pepperFile := filepath.Join(t.TempDir(), "peppers")
+	require.NoError(t, os.WriteFile(pepperFile, []byte("# rotated on 2026-01-01\n1:first-pepper-secret\n2:second-pepper-secret\n"), 0600))
+	viper.Set("password_hashing.pepper.file", pepperFile)
+	viper.Set("password_hashing.pepper.version", 2)
+
+	hasher, err := service.NewPasswordHasher()
+	require.NoError(t, err)
+	_, pepperVersion, err := hasher.Hash("Password123!")
+	require.NoError(t, err)
+	assert.Equal(t, 2, pepperVersion)
+
+	testCases := map[string]struct {
+		secrets string
+		version int
+	}{
+		"current version missing": {secrets: "1:first-pepper-secret", version: 2},
+		"secret too short":        {secrets: "1:short", version: 1},
+		"version missing":         {secrets: "first-pepper-secret", version: 0},
+		"version listed twice":    {secrets: "1:first-pepper-secret,1:second-pepper-secret", version: 1},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			setPasswordHashingConfig(service.HashAlgorithmArgon2id)
+			viper.Set("password_hashing.pepper.secrets", tc.secrets)
+			viper.Set("password_hashing.pepper.version", tc.version)
+
+			_, err := service.NewPasswordHasher()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func setPasswordHashingConfig(algorithm string) {
+	viper.Set("password_hashing.algorithm", algorithm)
+	viper.Set("password_hashing.argon2id.memory", 1024)
+	viper.Set("password_hashing.argon2id.iterations", 1)
+	viper.Set("password_hashing.argon2id.parallelism", 1)
+	viper.Set("password_hashing.argon2id.salt_length", 16)
+	viper.Set("password_hashing.argon2id.key_length", 32)
+	viper.Set("password_hashing.bcrypt_cost", 5)
+	viper.Set("password_hashing.pepper.file", "")
+	viper.Set("password_hashing.pepper.secrets", "")
+	viper.Set("password_hashing.pepper.version", 0)
+}
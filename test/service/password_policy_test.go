@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	testCases := []struct {
+		password string
+		score    int
+	}{
+		{password: "password", score: 0},
+		{password: "P@ssw0rd", score: 0},
+		{password: "aaaaaaaaaaaa", score: 0},
+		{password: "abcdef123456", score: 0},
+		{password: "Password123!", score: 1},
+		{password: "xK9#mQ2$vL", score: 4},
+		{password: "correct horse battery staple", score: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.password, func(t *testing.T) {
+			assert.Equal(t, tc.score, service.PasswordStrength(tc.password))
+		})
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	setPasswordPolicyConfig()
+	policy := service.NewPasswordPolicy(nil)
+
+	assert.NoError(t, policy.Validate("xK9mQ2vLwz", "Jane Doe", "jane.doe@example.com"))
+
+	testCases := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{name: "too short", password: "xK9mQ2v", rules: []string{"min_length"}},
+		{name: "over the bcrypt limit", password: "xK9mQ2vLwz" + string(make([]byte, 63)), rules: []string{"max_length"}},
+		{name: "missing classes", password: "xkcdmqvlwzrt", rules: []string{"uppercase", "digit"}},
+		{name: "name", password: "Doe4xK9mQ2vL", rules: []string{"user_info"}},
+		{name: "email local part", password: "JANE.DOE4xK9", rules: []string{"user_info"}},
+		{name: "weak", password: "Password1234", rules: []string{"strength"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password, "Jane Doe", "jane.doe@example.com")
+			require.Error(t, err)
+
+			policyErr, ok := err.(*service.PasswordPolicyError)
+			require.True(t, ok)
+
+			var rules []string
+			for _, violation := range policyErr.Violations {
+				rules = append(rules, violation.Rule)
+			}
+			assert.Equal(t, tc.rules, rules)
+		})
+	}
+}
+
+func setPasswordPolicyConfig() {
+	viper.Set("password_policy.min_length", 10)
+	viper.Set("password_policy.max_bytes", 0)
+	viper.Set("password_policy.require_upper", true)
+	viper.Set("password_policy.require_lower", true)
+	viper.Set("password_policy.require_digit", true)
+	viper.Set("password_policy.require_special", false)
+	viper.Set("password_policy.disallow_user_info", true)
+	viper.Set("password_policy.min_score", 3)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/YoubaImkf/go-auth-api/internal/model"
+	"github.com/YoubaImkf/go-auth-api/internal/service"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenServiceSigningAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		algorithm  string
+		privateKey any
+		kty        string
+	}{
+		{algorithm: "RS256", privateKey: rsaKey, kty: "RSA"},
+		{algorithm: "ES256", privateKey: ecKey, kty: "EC"},
+		{algorithm: "EdDSA", privateKey: edKey, kty: "OKP"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.algorithm, func(t *testing.T) {
+			setTokenConfig()
+			viper.Set("jwt.algorithm", tc.algorithm)
+			viper.Set("jwt.private_key_path", writePrivateKey(t, tc.privateKey))
+
+			tokenService, err := service.NewTokenService()
+			require.NoError(t, err)
+
+			token, _, err := tokenService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+			require.NoError(t, err)
+
+			claims, err := tokenService.ParseToken(token, service.AccessTokenType)
+			require.NoError(t, err)
+			assert.Equal(t, "john.doe@example.com", claims["sub"])
+
+			jwks := tokenService.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			assert.Equal(t, tc.algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestTokenServiceHS256DoesNotPublishKeys(t *testing.T) {
+	setTokenConfig()
+
+	tokenService, err := service.NewTokenService()
+	require.NoError(t, err)
+
+	assert.Empty(t, tokenService.JWKS().Keys)
+}
+
+func TestTokenServiceRejectsWrongTokenType(t *testing.T) {
+	setTokenConfig()
+
+	tokenService, err := service.NewTokenService()
+	require.NoError(t, err)
+
+	refreshToken, _, err := tokenService.GenerateToken("john.doe@example.com", "", service.RefreshTokenType)
+	require.NoError(t, err)
+
+	_, err = tokenService.ParseToken(refreshToken, service.AccessTokenType)
+	assert.Error(t, err)
+}
+
+func TestIssuedBeforeCutoff(t *testing.T) {
+	setTokenConfig()
+
+	tokenService, err := service.NewTokenService()
+	require.NoError(t, err)
+
+	user := &model.User{}
+	_, oldClaims, err := tokenService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+	assert.False(t, service.IssuedBeforeCutoff(user, oldClaims))
+
+	validAfter := time.Now().Truncate(time.Microsecond)
+	user.TokensValidAfter = &validAfter
+	time.Sleep(time.Millisecond)
+
+	_, newClaims, err := tokenService.GenerateToken("john.doe@example.com", "", service.AccessTokenType)
+	require.NoError(t, err)
+
+	assert.True(t, service.IssuedBeforeCutoff(user, oldClaims))
+	assert.False(t, service.IssuedBeforeCutoff(user, newClaims))
+}
+
+// --- Private Methods ---
+
+func setTokenConfig() {
+	viper.Reset()
+	viper.Set("jwt.secret", "test-secret")
+	viper.Set("jwt.issuer", "go-auth-api-test")
+	viper.Set("jwt.audience", "go-auth-api-test")
+	viper.Set("jwt.access_token_expiry", "15m")
+	viper.Set("jwt.refresh_token_expiry", "24h")
+}
+
+func writePrivateKey(t *testing.T, privateKey any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "private.pem")
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(path, pemBytes, 0600))
+	return path
+}
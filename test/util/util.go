@@ -8,24 +8,26 @@ import (
 )
 
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	JWTSecret  string
+	DBHost      string
+	DBPort      string
+	DBUser      string
+	DBPassword  string
+	DBName      string
+	JWTSecret   string
+	AdminAPIKey string
 }
 
 func LoadTestConfig() (*Config, error) {
 	os.Setenv("APP_ENVIRONMENT", "test")
 
 	config := &Config{
-		DBHost:     getEnvOrDefault("DATABASE_HOST", "localhost"),
-		DBPort:     getEnvOrDefault("DATABASE_PORT", "5432"),
-		DBUser:     getEnvOrDefault("POSTGRES_USER", "root"),
-		DBPassword: getEnvOrDefault("POSTGRES_PASSWORD", "lets-jungle-it-bro!"),
-		DBName:     "go-auth-db-test",
-		JWTSecret:  "test-secret",
+		DBHost:      getEnvOrDefault("DATABASE_HOST", "localhost"),
+		DBPort:      getEnvOrDefault("DATABASE_PORT", "5432"),
+		DBUser:      getEnvOrDefault("POSTGRES_USER", "root"),
+		DBPassword:  getEnvOrDefault("POSTGRES_PASSWORD", "lets-jungle-it-bro!"),
+		DBName:      "go-auth-db-test",
+		JWTSecret:   "test-secret",
+		AdminAPIKey: "test-admin-key",
 	}
 
 	viper.SetConfigName("config.test")
@@ -33,8 +35,40 @@ func LoadTestConfig() (*Config, error) {
 	viper.AddConfigPath("../../configs")
 
 	viper.Set("jwt.secret", config.JWTSecret)
+	viper.Set("admin.api_key", config.AdminAPIKey)
 	viper.Set("jwt.access_token_expiry", "15m")
 	viper.Set("jwt.refresh_token_expiry", "24h")
+	viper.Set("jwt.issuer", "go-auth-api-test")
+	viper.Set("jwt.audience", "go-auth-api-test")
+	viper.Set("janitor.batch_size", 2)
+	viper.Set("janitor.session_retention", "48h")
+	viper.Set("password_policy.min_length", 8)
+	viper.Set("password_policy.max_bytes", 72)
+	viper.Set("password_policy.require_upper", true)
+	viper.Set("password_policy.require_lower", true)
+	viper.Set("password_policy.require_digit", true)
+	viper.Set("password_policy.require_special", true)
+	viper.Set("password_policy.disallow_user_info", true)
+	viper.Set("password_policy.min_score", 1)
+	viper.Set("password_policy.history_size", 3)
+	viper.Set("password_hashing.algorithm", "argon2id")
+	viper.Set("password_hashing.argon2id.memory", 1024)
+	viper.Set("password_hashing.argon2id.iterations", 1)
+	viper.Set("password_hashing.argon2id.parallelism", 1)
+	viper.Set("password_hashing.argon2id.salt_length", 16)
+	viper.Set("password_hashing.argon2id.key_length", 32)
+	viper.Set("password_hashing.bcrypt_cost", 4)
+	viper.Set("password_hashing.pepper.secrets", "")
+	viper.Set("password_hashing.pepper.version", 0)
+	viper.Set("email_verification.token_expiry", "24h")
+	viper.Set("email_change.token_expiry", "1h")
+	viper.Set("email_change.undo_expiry", "72h")
+	viper.Set("account_deletion.grace_period", "720h")
+	viper.Set("account_lockout.max_attempts", 5)
+	viper.Set("account_lockout.free_attempts", 3)
+	viper.Set("account_lockout.base_delay", "0s")
+	viper.Set("account_lockout.max_delay", "1m")
+	viper.Set("account_lockout.duration", "15m")
 
 	return config, nil
 }